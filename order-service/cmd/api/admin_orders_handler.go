@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+)
+
+func (app *application) adminListOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.AdminOrderSearch
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.UserID = int64(app.readInt(qs, "user_id", 0, v))
+	input.Status = app.readString(qs, "status", "")
+	input.PaymentStatus = app.readString(qs, "payment_status", "")
+	input.ProductName = app.readString(qs, "product_name", "")
+	input.CreatedFrom = app.readDate(qs, "created_from", v)
+	input.CreatedTo = app.readDate(qs, "created_to", v)
+
+	// created_to is inclusive for callers, so move it to the start of the next day.
+	if input.CreatedTo != nil {
+		next := input.CreatedTo.Add(24 * time.Hour)
+		input.CreatedTo = &next
+	}
+
+	input.Page = app.readInt(qs, "page", 1, v)
+	input.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Sort = app.readString(qs, "sort", "-created_at")
+	input.SortSafelist = []string{
+		"id", "-id",
+		"total_amount", "-total_amount",
+		"created_at", "-created_at",
+		"status", "-status",
+	}
+
+	v.Check(input.UserID >= 0, "user_id", "must be a positive integer")
+	if input.Status != "" {
+		v.Check(validator.In(input.Status,
+			data.StatusPending, data.StatusPaid, data.StatusProcessing,
+			data.StatusShipped, data.StatusDelivered, data.StatusCancelled,
+		), "status", "must be a valid status")
+	}
+	if input.PaymentStatus != "" {
+		v.Check(validator.In(input.PaymentStatus,
+			data.PaymentStatusUnpaid, data.PaymentStatusPaid, data.PaymentStatusRefunded,
+		), "payment_status", "must be a valid payment status")
+	}
+
+	data.ValidateFilters(v, input.Filters)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	orders, metadata, err := app.models.Orders.GetAllForAdmin(input.AdminOrderSearch, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"orders":   orders,
+		"metadata": metadata,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) adminGetOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	order, err := app.models.Orders.GetForAdmin(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	notes, err := app.models.OrderNotes.GetAllForOrder(order.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Viewing another user's order is itself an action worth recording.
+	user := app.contextGetUser(r)
+	err = app.models.Audit.Insert(&data.AuditEntry{
+		OrderID: order.ID,
+		ActorID: user.ID,
+		Action:  data.AuditActionViewed,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"order": order, "notes": notes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) adminUpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(validator.In(input.Status,
+		data.StatusPending, data.StatusPaid, data.StatusProcessing,
+		data.StatusShipped, data.StatusDelivered, data.StatusCancelled,
+	), "status", "must be a valid status")
+	v.Check(input.Reason != "", "reason", "must be provided")
+	v.Check(len(input.Reason) <= 1000, "reason", "must not exceed 1000 characters")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	order, err := app.models.Orders.GetForAdmin(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Orders.UpdateStatusForAdmin(order, input.Status, user.ID, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) adminCreateOrderNoteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Body string `json:"body"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	order, err := app.models.Orders.GetForAdmin(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	note := &data.OrderNote{
+		OrderID:  order.ID,
+		AuthorID: user.ID,
+		Body:     input.Body,
+	}
+
+	v := validator.New()
+	if data.ValidateOrderNote(v, note); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.OrderNotes.Insert(note)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"note": note}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) adminListOrderAuditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.models.Orders.GetForAdmin(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	entries, err := app.models.Audit.GetAllForOrder(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"audit": entries}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
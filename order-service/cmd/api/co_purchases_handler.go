@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+)
+
+// coPurchasesHandler feeds product-service's "frequently bought together"
+// recommendations. Callers pass back next_after_id as after_id to read on from where
+// they stopped.
+func (app *application) coPurchasesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	afterID := int64(app.readInt(qs, "after_id", 0, v))
+	limit := app.readInt(qs, "limit", 1000, v)
+
+	if data.ValidateCoPurchaseQuery(v, afterID, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	pairs, nextAfterID, err := app.models.CoPurchases.GetSince(afterID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"co_purchases": pairs, "next_after_id": nextAfterID}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
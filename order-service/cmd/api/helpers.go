@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
 	"github.com/go-chi/chi/v5"
@@ -164,6 +165,78 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 
 }
 
+// The readBool() helper reads a boolean value from the query string. If no matching key
+// could be found it returns the provided default value, and if the value isn't a valid
+// boolean it records an error in the Validator.
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+// The readDate() helper reads a YYYY-MM-DD date from the query string. It returns nil
+// if the key is missing, and records an error in the Validator if the value can't be
+// parsed.
+func (app *application) readDate(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		v.AddError(key, "must be a date in YYYY-MM-DD format")
+		return nil
+	}
+	return &t
+}
+
+// runEvery calls fn every interval, and straight away as well if immediately is set,
+// until the server shuts down. The loop is tracked by app.wg, so shutdown waits for a
+// run that is under way to finish rather than cutting it off.
+func (app *application) runEvery(interval time.Duration, immediately bool, fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		if immediately {
+			fn()
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// shuttingDown reports whether the server has started shutting down. Long-running
+// background work checks it between steps so that it stops promptly.
+func (app *application) shuttingDown() bool {
+	select {
+	case <-app.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
 // // the background helper accepts an arbitrary function as a parameter
 func (app *application) background(fn func()) {
 	// Increment the WaitGroup counter.
@@ -13,6 +13,7 @@ import (
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/cache"
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/events"
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/jsonlog"
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/jwt"
 	_ "github.com/lib/pq"
@@ -42,12 +43,24 @@ type config struct {
 	userService struct {
 		url string
 	}
+	productService struct {
+		url string
+	}
 	cache struct {
 		userTTL time.Duration
 	}
 	cors struct {
 		trustedOrigins []string
 	}
+	sse struct {
+		heartbeat time.Duration
+	}
+	messages struct {
+		hourlyLimit int
+	}
+	sellers struct {
+		backfillInterval time.Duration
+	}
 }
 
 type application struct {
@@ -58,6 +71,8 @@ type application struct {
 	jwtValidator *jwt.JWTValidator
 	httpClient   *http.Client
 	userCache    *cache.UserCache
+	events       *events.Broker
+	shutdown     chan struct{}
 }
 
 func main() {
@@ -86,9 +101,21 @@ func main() {
 	// User service config
 	flag.StringVar(&cfg.userService.url, "user-service-url", os.Getenv("USER_SERVICE_URL"), "User service URL")
 
+	// Product service config
+	flag.StringVar(&cfg.productService.url, "product-service-url", os.Getenv("PRODUCT_SERVICE_URL"), "Product service URL")
+
 	// Cache config
 	flag.DurationVar(&cfg.cache.userTTL, "cache-user-ttl", 5*time.Minute, "User cache TTL")
 
+	// Server-Sent Events config
+	flag.DurationVar(&cfg.sse.heartbeat, "sse-heartbeat", 15*time.Second, "Interval between SSE heartbeat comments")
+
+	// Order messaging config
+	flag.IntVar(&cfg.messages.hourlyLimit, "messages-hourly-limit", 30, "Maximum order messages a user may send per hour")
+
+	// Seller attribution config
+	flag.DurationVar(&cfg.sellers.backfillInterval, "seller-backfill-interval", time.Hour, "Interval between attempts to attribute order items with no known seller")
+
 	// Use the flag.Func() function to process the -cors-trusted-origins command line
 	// flag.
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
@@ -103,6 +130,9 @@ func main() {
 	if cfg.userService.url == "" {
 		logger.PrintFatal(errors.New("USER_SERVICE_URL is required"), nil)
 	}
+	if cfg.productService.url == "" {
+		logger.PrintFatal(errors.New("PRODUCT_SERVICE_URL is required"), nil)
+	}
 
 	// Database connection
 	db, err := openDB(cfg)
@@ -135,15 +165,30 @@ func main() {
 		"ttl": cfg.cache.userTTL.String(),
 	})
 
+	models := data.NewModels(db)
+
+	// Order events are pushed to connected streams from Postgres NOTIFY.
+	broker := events.NewBroker(models.Events, logger)
+	go func() {
+		if err := broker.Listen(cfg.db.dsn); err != nil {
+			logger.PrintError(err, map[string]string{"component": "order_events_listener"})
+		}
+	}()
+	logger.PrintInfo("order events listener started", nil)
+
 	app := &application{
 		config:       cfg,
 		logger:       logger,
-		models:       data.NewModels(db),
+		models:       models,
 		jwtValidator: jwtValidator,
 		httpClient:   httpClient,
 		userCache:    userCache,
+		events:       broker,
+		shutdown:     make(chan struct{}),
 	}
 
+	app.startSellerBackfill()
+
 	err = app.serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -189,6 +189,22 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	return app.requireAuthenticatedUser(fn)
 }
 
+// requirePermission checks that the user holds a specific permission code. Unlike
+// user-service we don't own the permissions tables, so the codes come from the user
+// details fetched (and cached) during authenticate().
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !user.Permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	// Wrap this with the requireActivatedUser() middleware before returning it.
+	return app.requireActivatedUser(fn)
+}
+
 // if your code makes a decision about what to return based on the content of a request header,
 // you should include that header name in your Vary response header — even if the request
 // didn’t include that header
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+)
+
+// orderEventsHandler streams the changes to a single order as Server-Sent Events.
+func (app *application) orderEventsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	// Make sure the order exists and belongs to the user before streaming anything.
+	_, err = app.models.Orders.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.streamOrderEvents(w, r, user.ID, id)
+}
+
+// userOrderEventsHandler streams the changes to all of the user's orders.
+func (app *application) userOrderEventsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	app.streamOrderEvents(w, r, user.ID, 0)
+}
+
+// streamOrderEvents keeps the connection open and writes each matching order event as
+// it happens. Clients that reconnect with a Last-Event-ID header are first sent the
+// events they missed. The stream ends when the client goes away, when the server shuts
+// down, or when the client falls too far behind.
+//
+// Event IDs are taken inside the order transactions, so events can commit, and be
+// published, out of ID order. Live events are therefore only skipped if they were
+// just replayed, never for having a lower ID than one already sent.
+func (app *application) streamOrderEvents(w http.ResponseWriter, r *http.Request, userID, orderID int64) {
+	var lastID int64
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id < 0 {
+			app.badRequestResponse(w, r, errors.New("invalid Last-Event-ID header"))
+			return
+		}
+		lastID = id
+	}
+
+	// The server's WriteTimeout would otherwise cut every stream off after 30 seconds.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Subscribe before replaying so nothing published in between is lost. Events
+	// sent during the replay are skipped below when they come through again.
+	ch, unsubscribe := app.events.Subscribe(userID, orderID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// Ask clients to wait a few seconds before reconnecting, e.g. during a deploy.
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	// The missed events are read a batch at a time until they run out, so that a
+	// client that was away for a long time still gets all of them. Only the last
+	// batch, the newest events, can overlap with those published since subscribing,
+	// so only its IDs are remembered.
+	replayed := map[int64]bool{}
+	for lastID > 0 {
+		missed, err := app.models.Events.GetSince(userID, orderID, lastID)
+		if err != nil {
+			app.logError(r, err)
+			return
+		}
+		clear(replayed)
+		for _, event := range missed {
+			if err := writeSSEEvent(w, event); err != nil {
+				return
+			}
+			replayed[event.ID] = true
+			lastID = event.ID
+		}
+		if len(missed) < data.OrderEventBatchSize {
+			break
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(app.config.sse.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			// Each event is published once, so a replayed one is only skipped once.
+			if replayed[event.ID] {
+				delete(replayed, event.ID)
+				continue
+			}
+			if err := writeSSEEvent(w, event); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, event *data.OrderEvent) error {
+	js, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, js)
+	return err
+}
@@ -19,6 +19,7 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 
 	var input struct {
 		ProductID       int64   `json:"product_id"`
+		VariantID       *int64  `json:"variant_id,omitempty"`
 		ProductName     string  `json:"product_name"`
 		ProductImageURL *string `json:"product_image_url,omitempty"`
 		UnitPrice       float64 `json:"unit_price"`
@@ -45,6 +46,7 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 	item := &data.OrderItem{
 		OrderID:         orderID,
 		ProductID:       input.ProductID,
+		VariantID:       input.VariantID,
 		ProductName:     input.ProductName,
 		ProductImageURL: input.ProductImageURL,
 		UnitPrice:       input.UnitPrice,
@@ -63,6 +65,14 @@ func (app *application) createOrderItemHandler(w http.ResponseWriter, r *http.Re
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+
+	items := []data.OrderItem{*item}
+	app.resolveItemSellers(items, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	item.SellerID = items[0].SellerID
 	err = app.models.OrderItems.Insert(item)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
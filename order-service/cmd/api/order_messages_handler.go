@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+)
+
+// messageThreadRole works out how the current user may take part in an order's
+// message thread. It writes the error response itself and returns "" if the user may
+// not see the thread.
+func (app *application) messageThreadRole(w http.ResponseWriter, r *http.Request, orderID int64) string {
+	user := app.contextGetUser(r)
+
+	role, err := app.models.OrderMessages.ParticipantRole(orderID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return ""
+	}
+
+	if role == "" && user.Permissions.Include("orders:admin") {
+		role = data.RoleAdmin
+	}
+
+	// Don't reveal that someone else's order exists.
+	if role == "" {
+		app.notFoundResponse(w, r)
+	}
+	return role
+}
+
+func (app *application) listOrderMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Page = app.readInt(qs, "page", 1, v)
+	input.PageSize = app.readInt(qs, "page_size", 50, v)
+	input.Sort = app.readString(qs, "sort", "id")
+	input.SortSafelist = []string{"id", "-id"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	role := app.messageThreadRole(w, r, id)
+	if role == "" {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	unread, err := app.models.OrderMessages.UnreadForOrder(id, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	messages, metadata, err := app.models.OrderMessages.GetAllForOrder(id, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Everything up to the newest message on this page has now been seen.
+	var newest int64
+	for _, message := range messages {
+		if message.ID > newest {
+			newest = message.ID
+		}
+	}
+	if newest > 0 {
+		err = app.models.OrderMessages.MarkRead(id, user.ID, newest)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"messages": messages,
+		"unread":   unread,
+		"metadata": metadata,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createOrderMessageHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Body           string   `json:"body"`
+		AttachmentURLs []string `json:"attachment_urls"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	role := app.messageThreadRole(w, r, id)
+	if role == "" {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	message := &data.OrderMessage{
+		OrderID:        id,
+		SenderID:       user.ID,
+		SenderRole:     role,
+		Body:           input.Body,
+		AttachmentURLs: input.AttachmentURLs,
+	}
+	if message.AttachmentURLs == nil {
+		message.AttachmentURLs = []string{}
+	}
+
+	v := validator.New()
+	if data.ValidateOrderMessage(v, message); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Staff aren't subject to the abuse limit.
+	limit := app.config.messages.hourlyLimit
+	if role == data.RoleAdmin {
+		limit = 0
+	}
+
+	err = app.models.OrderMessages.Insert(message, limit, time.Now().Add(-time.Hour))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrMessageLimit):
+			app.rateLimitExceededResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The sender has obviously read their own thread up to this point.
+	err = app.models.OrderMessages.MarkRead(id, user.ID, message.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/orders/%d/messages", id))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"message": message}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unreadOrderMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	counts, err := app.models.OrderMessages.UnreadForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	total := 0
+	for _, c := range counts {
+		total += c.Unread
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"unread": counts, "total_unread": total}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
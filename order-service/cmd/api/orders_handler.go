@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
@@ -57,6 +58,12 @@ func (app *application) createOrderHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	app.resolveItemSellers(order.Items, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	err = app.models.Orders.Insert(order)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -107,6 +114,37 @@ func (app *application) getOrderHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// purchaseHandler tells the user whether they have received a product, and in which
+// order. product-service calls it on the user's behalf to verify review authors.
+func (app *application) purchaseHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := app.readIDParam(r, "product_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	orderID, err := app.models.Orders.GetDeliveredOrderForProduct(user.ID, productID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"purchase": envelope{
+		"product_id": productID,
+		"order_id":   orderID,
+	}}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) updateOrderHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r, "id")
 	if err != nil {
@@ -241,8 +279,12 @@ func (app *application) listOrderHandler(w http.ResponseWriter, r *http.Request)
 	v := validator.New()
 	qs := r.URL.Query()
 
+	// cursor=next_cursor carries on from a previous page in place of page. Counting
+	// the orders is optional: by default only page-numbered lists do it.
 	input.Page = app.readInt(qs, "page", 1, v)
 	input.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Cursor = app.readString(qs, "cursor", "")
+	input.WithTotal = app.readBool(qs, "total", input.Cursor == "", v)
 	input.Sort = app.readString(qs, "sort", "id")
 	input.SortSafelist = []string{
 		"id", "-id",
@@ -281,3 +323,170 @@ func (app *application) listOrderHandler(w http.ResponseWriter, r *http.Request)
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// reorderChange describes how an item from the original order differs when it is
+// ordered again.
+type reorderChange struct {
+	ProductID   int64    `json:"product_id"`
+	VariantID   *int64   `json:"variant_id,omitempty"`
+	ProductName string   `json:"product_name"`
+	Reason      string   `json:"reason"`
+	OldPrice    *float64 `json:"old_price,omitempty"`
+	NewPrice    *float64 `json:"new_price,omitempty"`
+	Requested   *int     `json:"requested_quantity,omitempty"`
+	Available   *int     `json:"available_quantity,omitempty"`
+}
+
+// reorderHandler builds a new pending order from the items of a previous order, using
+// current prices and stock from product-service. Anything that couldn't be carried
+// over unchanged is listed in the "changes" field of the response.
+func (app *application) reorderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	previous, err := app.models.Orders.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	order := &data.Order{
+		UserID:          user.ID,
+		Currency:        previous.Currency,
+		Status:          data.StatusPending,
+		PaymentStatus:   data.PaymentStatusUnpaid,
+		ShippingAddress: previous.ShippingAddress,
+		Items:           []data.OrderItem{},
+	}
+	changes := []reorderChange{}
+
+	for _, old := range previous.Items {
+		product, err := app.getProductFromProductService(old.ProductID)
+		if err != nil {
+			if errors.Is(err, errProductNotFound) {
+				changes = append(changes, reorderChange{
+					ProductID:   old.ProductID,
+					ProductName: old.ProductName,
+					Reason:      "unavailable",
+				})
+				continue
+			}
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// Items ordered in a particular size or colour use that variant's stock and,
+		// if it overrides it, its price.
+		price, stock, imageURL := product.Price, product.Stock, product.ImageURL
+		if old.VariantID != nil {
+			variant := product.Variant(*old.VariantID)
+			if variant == nil {
+				changes = append(changes, reorderChange{
+					ProductID:   old.ProductID,
+					VariantID:   old.VariantID,
+					ProductName: product.Name,
+					Reason:      "unavailable",
+				})
+				continue
+			}
+			stock = variant.Stock
+			if variant.Price != nil {
+				price = *variant.Price
+			}
+			if variant.ImageURL != "" {
+				imageURL = variant.ImageURL
+			}
+		}
+
+		if stock <= 0 {
+			changes = append(changes, reorderChange{
+				ProductID:   old.ProductID,
+				VariantID:   old.VariantID,
+				ProductName: product.Name,
+				Reason:      "out_of_stock",
+			})
+			continue
+		}
+
+		quantity := old.Quantity
+		if int(stock) < quantity {
+			requested, available := quantity, int(stock)
+			changes = append(changes, reorderChange{
+				ProductID:   old.ProductID,
+				VariantID:   old.VariantID,
+				ProductName: product.Name,
+				Reason:      "quantity_reduced",
+				Requested:   &requested,
+				Available:   &available,
+			})
+			quantity = available
+		}
+
+		if price != old.UnitPrice {
+			oldPrice, newPrice := old.UnitPrice, price
+			changes = append(changes, reorderChange{
+				ProductID:   old.ProductID,
+				VariantID:   old.VariantID,
+				ProductName: product.Name,
+				Reason:      "price_changed",
+				OldPrice:    &oldPrice,
+				NewPrice:    &newPrice,
+			})
+		}
+
+		item := data.OrderItem{
+			ProductID:   product.ID,
+			VariantID:   old.VariantID,
+			SellerID:    product.UserID,
+			ProductName: product.Name,
+			UnitPrice:   price,
+			Quantity:    quantity,
+		}
+		if imageURL != "" {
+			item.ProductImageURL = &imageURL
+		}
+
+		order.Items = append(order.Items, item)
+		order.TotalAmount += item.UnitPrice * float64(item.Quantity)
+	}
+
+	order.TotalAmount = math.Round(order.TotalAmount*100) / 100
+
+	if len(order.Items) == 0 {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, envelope{
+			"message": "none of the products in this order can be ordered again",
+			"changes": changes,
+		})
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateOrder(v, order); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Orders.Insert(order)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/orders/%d", order.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"order": order, "changes": changes}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
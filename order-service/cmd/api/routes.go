@@ -21,12 +21,20 @@ func (app *application) routes() http.Handler {
 	// Public routes
 	// router.MethodFunc(http.MethodGet, "/v1/orders", app.listOrderHandler)
 	router.MethodFunc(http.MethodGet, "/v1/orders/{id}", app.getOrderHandler)
+	router.MethodFunc(http.MethodGet, "/v1/orders/events", app.requireActivatedUser(app.userOrderEventsHandler))
+	router.MethodFunc(http.MethodGet, "/v1/orders/{id}/events", app.requireActivatedUser(app.orderEventsHandler))
+	router.MethodFunc(http.MethodGet, "/v1/orders/messages/unread", app.requireActivatedUser(app.unreadOrderMessagesHandler))
+	router.MethodFunc(http.MethodGet, "/v1/orders/{id}/messages", app.requireActivatedUser(app.listOrderMessagesHandler))
+	router.MethodFunc(http.MethodPost, "/v1/orders/{id}/messages", app.requireActivatedUser(app.createOrderMessageHandler))
 	router.MethodFunc(http.MethodGet, "/v1/orders", app.listOrderHandler)
 
 	// Protected routes - require activated user
 	router.MethodFunc(http.MethodPost, "/v1/orders", app.requireActivatedUser(app.createOrderHandler))
 	router.MethodFunc(http.MethodPatch, "/v1/orders/{id}", app.requireActivatedUser(app.updateOrderHandler))
 	router.MethodFunc(http.MethodDelete, "/v1/orders/{id}", app.requireActivatedUser(app.deleteOrderHandler))
+	router.MethodFunc(http.MethodPost, "/v1/orders/{id}/reorder", app.requireActivatedUser(app.reorderHandler))
+	router.MethodFunc(http.MethodGet, "/v1/orders/purchases/{product_id}", app.requireActivatedUser(app.purchaseHandler))
+	router.MethodFunc(http.MethodGet, "/v1/orders/co-purchases", app.requirePermission("orders:co-purchases", app.coPurchasesHandler))
 
 	// Protected routes - require activated user
 	router.MethodFunc(http.MethodPost, "/v1/orders/{order_id}/items", app.requireActivatedUser(app.createOrderItemHandler))
@@ -34,6 +42,16 @@ func (app *application) routes() http.Handler {
 	router.MethodFunc(http.MethodPatch, "/v1/orders/{order_id}/items/{id}", app.requireActivatedUser(app.updateOrderItemHandler))
 	router.MethodFunc(http.MethodDelete, "/v1/orders/{order_id}/items/{id}", app.requireActivatedUser(app.deleteOrderItemHandler))
 
+	// Seller routes - analytics for the caller's own products
+	router.MethodFunc(http.MethodGet, "/v1/seller/analytics", app.requireActivatedUser(app.sellerAnalyticsHandler))
+
+	// Admin routes - require the orders:admin permission
+	router.MethodFunc(http.MethodGet, "/v1/admin/orders", app.requirePermission("orders:admin", app.adminListOrdersHandler))
+	router.MethodFunc(http.MethodGet, "/v1/admin/orders/{id}", app.requirePermission("orders:admin", app.adminGetOrderHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/admin/orders/{id}/status", app.requirePermission("orders:admin", app.adminUpdateOrderStatusHandler))
+	router.MethodFunc(http.MethodPost, "/v1/admin/orders/{id}/notes", app.requirePermission("orders:admin", app.adminCreateOrderNoteHandler))
+	router.MethodFunc(http.MethodGet, "/v1/admin/orders/{id}/audit", app.requirePermission("orders:admin", app.adminListOrderAuditHandler))
+
 	router.Method(http.MethodGet, "/debug/vars", expvar.Handler())
 
 	// Return the Chi router, which implements http.Handler
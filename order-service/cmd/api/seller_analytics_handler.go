@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+)
+
+func (app *application) sellerAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	// Default to the last 30 days, including today.
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to := today
+	from := today.AddDate(0, 0, -29)
+
+	if t := app.readDate(qs, "from", v); t != nil {
+		from = *t
+	}
+	if t := app.readDate(qs, "to", v); t != nil {
+		to = *t
+	}
+	granularity := app.readString(qs, "granularity", data.GranularityDay)
+	topN := app.readInt(qs, "top", 10, v)
+
+	if data.ValidateAnalyticsRange(v, from, to, granularity, topN); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	analytics, err := app.models.Analytics.Get(user.ID, from, to, granularity, topN)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"analytics": analytics}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
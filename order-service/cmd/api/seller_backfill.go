@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// sellerBackfillBatchSize is how many products are looked up per batch.
+const sellerBackfillBatchSize = 100
+
+// startSellerBackfill attributes the order items with no known seller, those placed
+// before sellers were recorded or while product-service couldn't be reached, on
+// startup and then every seller-backfill-interval, until the server shuts down.
+func (app *application) startSellerBackfill() {
+	app.runEvery(app.config.sellers.backfillInterval, true, app.backfillSellers)
+}
+
+// backfillSellers looks up the seller of each product that has unattributed order
+// items and assigns its items to them, which adds them to the seller's sales. Products
+// that no longer exist are skipped, and their items stay unattributed. It stops early
+// if product-service can't be reached or the server starts shutting down.
+func (app *application) backfillSellers() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.PrintError(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	var afterID int64
+	assigned := 0
+
+	for !app.shuttingDown() {
+		productIDs, err := app.models.OrderItems.GetUnattributedProducts(afterID, sellerBackfillBatchSize)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"component": "sellers"})
+			return
+		}
+
+		for _, productID := range productIDs {
+			afterID = productID
+
+			product, err := app.getProductFromProductService(productID)
+			if err != nil {
+				if errors.Is(err, errProductNotFound) {
+					continue
+				}
+				app.logger.PrintError(err, map[string]string{"component": "sellers"})
+				return
+			}
+
+			n, err := app.models.OrderItems.AssignSeller(productID, product.UserID)
+			assigned += n
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"component": "sellers"})
+				return
+			}
+		}
+
+		if len(productIDs) < sellerBackfillBatchSize {
+			break
+		}
+	}
+
+	if assigned > 0 {
+		app.logger.PrintInfo("attributed order items to sellers", map[string]string{
+			"items": fmt.Sprintf("%d", assigned),
+		})
+	}
+}
@@ -20,6 +20,10 @@ func (app *application) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Shutdown() doesn't interrupt active connections, so open event streams would hold
+	// it up until the deadline. Closing the broker ends every stream straight away.
+	srv.RegisterOnShutdown(app.events.Close)
+
 	//// Create a shutdownError channel. We will use this to receive any errors returned
 	// by the graceful Shutdown() function.
 
@@ -58,6 +62,9 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		// Tell the periodic background workers to stop once their current run is done.
+		close(app.shutdown)
+
 		// Log a message to say that we're waiting for any background goroutines to
 		// complete their tasks.
 		app.logger.PrintInfo("completing background tasks", map[string]string{
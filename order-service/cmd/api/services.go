@@ -3,11 +3,15 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
 )
 
 // getUserFromUserService fetches user details from the user-service.
@@ -45,10 +49,11 @@ func (app *application) getUserFromUserService(userID int64) (*data.User, error)
 	// Parse the response
 	var envelope struct {
 		User struct {
-			ID        int64  `json:"id"`
-			Email     string `json:"email"`
-			Name      string `json:"name"`
-			Activated bool   `json:"activated"`
+			ID          int64    `json:"id"`
+			Email       string   `json:"email"`
+			Name        string   `json:"name"`
+			Activated   bool     `json:"activated"`
+			Permissions []string `json:"permissions"`
 		} `json:"user"`
 	}
 
@@ -59,10 +64,11 @@ func (app *application) getUserFromUserService(userID int64) (*data.User, error)
 
 	// Convert to internal User type
 	user := &data.User{
-		ID:        envelope.User.ID,
-		Email:     envelope.User.Email,
-		Name:      envelope.User.Name,
-		Activated: envelope.User.Activated,
+		ID:          envelope.User.ID,
+		Email:       envelope.User.Email,
+		Name:        envelope.User.Name,
+		Activated:   envelope.User.Activated,
+		Permissions: envelope.User.Permissions,
 	}
 
 	return user, nil
@@ -114,3 +120,137 @@ func (app *application) invalidateUserCache(userID int64) {
 		})
 	}
 }
+
+var errProductNotFound = errors.New("product not found")
+
+// getProductFromProductService fetches the current details of a product from
+// product-service. It returns errProductNotFound if the product no longer exists.
+func (app *application) getProductFromProductService(productID int64) (*data.Product, error) {
+	url := fmt.Sprintf("%s/v1/products/%d", app.config.productService.url, productID)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	ctx, cancel := app.createRequestContext(3 * time.Second)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	resp, err := app.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("product-service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errProductNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("product-service returned status %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Product struct {
+			ID     int64  `json:"id"`
+			UserID int64  `json:"user_id"`
+			Name   string `json:"name"`
+			Price  string `json:"price"`
+			// EffectivePrice is the price after any sale, and is what is charged.
+			EffectivePrice string `json:"effective_price"`
+			ImageURL       string `json:"image_url"`
+			Stock          int32  `json:"stock"`
+			Variants       []struct {
+				ID       int64   `json:"id"`
+				Price    *string `json:"price"`
+				ImageURL *string `json:"image_url"`
+				Stock    int32   `json:"stock"`
+			} `json:"variants"`
+		} `json:"product"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&envelope)
+	if err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	// Older versions of product-service only sent the price.
+	priceText := envelope.Product.EffectivePrice
+	if priceText == "" {
+		priceText = envelope.Product.Price
+	}
+	price, err := parseProductPrice(priceText)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &data.Product{
+		ID:       envelope.Product.ID,
+		UserID:   envelope.Product.UserID,
+		Name:     envelope.Product.Name,
+		Price:    price,
+		ImageURL: envelope.Product.ImageURL,
+		Stock:    envelope.Product.Stock,
+		Variants: []data.ProductVariant{},
+	}
+
+	for _, v := range envelope.Product.Variants {
+		variant := data.ProductVariant{ID: v.ID, Stock: v.Stock}
+		if v.Price != nil {
+			variantPrice, err := parseProductPrice(*v.Price)
+			if err != nil {
+				return nil, err
+			}
+			variant.Price = &variantPrice
+		}
+		if v.ImageURL != nil {
+			variant.ImageURL = *v.ImageURL
+		}
+		product.Variants = append(product.Variants, variant)
+	}
+
+	return product, nil
+}
+
+// parseProductPrice parses a price as formatted by product-service, e.g. "$ 79.99".
+func parseProductPrice(s string) (float64, error) {
+	price, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(s, "$")), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse product price %q: %w", s, err)
+	}
+	return price, nil
+}
+
+// resolveItemSellers looks up each item's product and records who sells it, so that
+// sales can be attributed to sellers. Items whose product doesn't exist, or whose
+// variant isn't one of the product's, are reported as validation errors. If
+// product-service can't be reached the order still goes through: its items are left
+// with no known seller and unchecked, and the seller backfill attributes them later.
+func (app *application) resolveItemSellers(items []data.OrderItem, v *validator.Validator) {
+	unreachable := false
+	for i := range items {
+		items[i].SellerID = 0
+		if unreachable {
+			continue
+		}
+
+		product, err := app.getProductFromProductService(items[i].ProductID)
+		if err != nil {
+			if errors.Is(err, errProductNotFound) {
+				v.AddError(fmt.Sprintf("items[%d].product_id", i), "product does not exist")
+				continue
+			}
+			app.logger.PrintError(err, map[string]string{
+				"component":  "sellers",
+				"product_id": fmt.Sprintf("%d", items[i].ProductID),
+			})
+			unreachable = true
+			continue
+		}
+		items[i].SellerID = product.UserID
+
+		if items[i].VariantID != nil && product.Variant(*items[i].VariantID) == nil {
+			v.AddError(fmt.Sprintf("items[%d].variant_id", i), "variant does not exist for this product")
+		}
+	}
+}
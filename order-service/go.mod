@@ -3,7 +3,7 @@ module github.com/PaulBabatuyi/FashionMarket-Backend/order-service
 go 1.25.0
 
 require (
-	github.com/felixge/httpsnoop v1.0.2
+	github.com/felixge/httpsnoop v1.0.4
 	github.com/go-chi/chi/v5 v5.2.3
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/lib/pq v1.10.9
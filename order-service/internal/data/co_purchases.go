@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+)
+
+// coPurchaseSettle is how old an order item must be before it is reported. Item IDs
+// are handed out before their transactions commit, so a newer item can be visible
+// before an older one; waiting a little means a reader that remembers the last ID it
+// saw doesn't skip any.
+const coPurchaseSettle = time.Minute
+
+// CoPurchase is the number of orders in which two products were bought together.
+type CoPurchase struct {
+	ProductID      int64 `json:"product_id"`
+	OtherProductID int64 `json:"other_product_id"`
+	Orders         int   `json:"orders"`
+}
+
+type CoPurchaseModel struct {
+	DB *sql.DB
+}
+
+func ValidateCoPurchaseQuery(v *validator.Validator, afterID int64, limit int) {
+	v.Check(afterID >= 0, "after_id", "must be zero or greater")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 5000, "limit", "must be a maximum of 5000")
+}
+
+// GetSince returns the product pairs completed by up to limit order items with IDs
+// after afterID, and the ID of the last of those items, which is the afterID of the
+// next call. Each pair is reported once per order, when the later of its two items
+// is read, so adding up the results of successive calls counts every order exactly
+// once.
+//
+// Orders already cancelled when their items are read are left out. An order cancelled
+// after it was reported, i.e. more than coPurchaseSettle after it was placed, stays in
+// the counts for good: nothing is fed back to take it out. This is a deliberate
+// limitation. Late cancellations are few, the customer did choose those products
+// together, and subtracting them would need a second feed keyed on status changes
+// rather than on order item IDs.
+func (m CoPurchaseModel) GetSince(afterID int64, limit int) ([]CoPurchase, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var lastID int64
+	err := m.DB.QueryRowContext(ctx, `
+        SELECT COALESCE(max(id), $1)
+        FROM (
+            SELECT id FROM order_items
+            WHERE id > $1 AND created_at < NOW() - $3 * INTERVAL '1 second'
+            ORDER BY id
+            LIMIT $2
+        ) AS batch`, afterID, limit, coPurchaseSettle.Seconds()).Scan(&lastID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pairs := []CoPurchase{}
+	if lastID == afterID {
+		return pairs, lastID, nil
+	}
+
+	// A product ordered more than once in the same order, say in two sizes, only
+	// counts by its first item.
+	query := `
+        SELECT b.product_id, j.product_id, count(*)
+        FROM order_items b
+        JOIN orders o ON o.id = b.order_id AND o.status <> 'cancelled'
+        JOIN order_items j ON j.order_id = b.order_id AND j.id < b.id AND j.product_id <> b.product_id
+        WHERE b.id > $1 AND b.id <= $2
+        AND NOT EXISTS (
+            SELECT 1 FROM order_items e WHERE e.order_id = b.order_id AND e.product_id = b.product_id AND e.id < b.id)
+        AND NOT EXISTS (
+            SELECT 1 FROM order_items e WHERE e.order_id = j.order_id AND e.product_id = j.product_id AND e.id < j.id)
+        GROUP BY b.product_id, j.product_id`
+
+	rows, err := m.DB.QueryContext(ctx, query, afterID, lastID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pair CoPurchase
+		if err := rows.Scan(&pair.ProductID, &pair.OtherProductID, &pair.Orders); err != nil {
+			return nil, 0, err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return pairs, lastID, nil
+}
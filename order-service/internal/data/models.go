@@ -11,13 +11,25 @@ var (
 )
 
 type Models struct {
-	Orders     OrderModel
-	OrderItems OrderItemModel
+	Orders        OrderModel
+	OrderItems    OrderItemModel
+	OrderNotes    OrderNoteModel
+	Audit         AuditModel
+	Analytics     SellerAnalyticsModel
+	Events        OrderEventModel
+	OrderMessages OrderMessageModel
+	CoPurchases   CoPurchaseModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Orders:     OrderModel{DB: db},
-		OrderItems: OrderItemModel{DB: db},
+		Orders:        OrderModel{DB: db},
+		OrderItems:    OrderItemModel{DB: db},
+		OrderNotes:    OrderNoteModel{DB: db},
+		Audit:         AuditModel{DB: db},
+		Analytics:     SellerAnalyticsModel{DB: db},
+		Events:        OrderEventModel{DB: db},
+		OrderMessages: OrderMessageModel{DB: db},
+		CoPurchases:   CoPurchaseModel{DB: db},
 	}
 }
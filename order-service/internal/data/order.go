@@ -3,8 +3,11 @@ package data
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
@@ -29,6 +32,21 @@ type ShippingAddress struct {
 	Country string `json:"country"`
 }
 
+// Value implements driver.Valuer so the address can be written to the JSONB
+// shipping_address column.
+func (a ShippingAddress) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Scan implements sql.Scanner for reading the JSONB shipping_address column.
+func (a *ShippingAddress) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("shipping_address: expected []byte")
+	}
+	return json.Unmarshal(b, a)
+}
+
 type Order struct {
 	ID              int64           `json:"id"`
 	UserID          int64           `json:"user_id"`
@@ -94,14 +112,16 @@ func (o OrderModel) Insert(order *Order) error {
 
 func (o OrderModel) insertItemTx(ctx context.Context, tx *sql.Tx, item *OrderItem) error {
 	query := `
-    INSERT INTO order_items (order_id, product_id, product_name, product_image_url,
-	 unit_price, quantity)
-    VALUES ($1, $2, $3, $4, $5, $6)
+    INSERT INTO order_items (order_id, product_id, variant_id, seller_id, product_name,
+	 product_image_url, unit_price, quantity)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
     RETURNING id, subtotal, created_at`
 
 	return tx.QueryRowContext(ctx, query,
 		item.OrderID,
 		item.ProductID,
+		item.VariantID,
+		item.SellerID,
 		item.ProductName,
 		item.ProductImageURL,
 		item.UnitPrice,
@@ -155,19 +175,32 @@ func (o OrderModel) Get(id, user_id int64) (*Order, error) {
 
 	return &order, nil
 }
+
+// GetAll returns a page of the user's orders. Pages are read from filters.Cursor if it
+// is set, and by offset otherwise; either way one more order than the page size is
+// read, to tell whether there is a next page. The orders are only counted if
+// filters.WithTotal is set.
 func (m OrderModel) GetAll(userID int64, filters Filters) ([]*Order, Metadata, error) {
+	total := "0"
+	if filters.WithTotal {
+		// Counted separately, since the cursor's condition mustn't affect it.
+		total = "(SELECT count(*) FROM orders WHERE user_id = $1)"
+	}
+	keyset, keysetArgs := filters.keyset(4)
+
 	query := fmt.Sprintf(`
-        SELECT count(*) OVER(), id, user_id, total_amount, currency, status, payment_status,
-               shipping_address, created_at, updated_at, version
+        SELECT %[3]s, id, user_id, total_amount, currency, status, payment_status,
+               shipping_address, created_at, updated_at, version, %[1]s::text
         FROM orders
-        WHERE user_id = $1
-        ORDER BY %s %s, id ASC
-        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+        WHERE user_id = $1 AND %[4]s
+        ORDER BY %[1]s %[2]s, id ASC
+        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection(), total, keyset)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{userID, filters.limit(), filters.offset()}
+	args := []interface{}{userID, filters.limit() + 1, filters.offset()}
+	args = append(args, keysetArgs...)
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -177,9 +210,11 @@ func (m OrderModel) GetAll(userID int64, filters Filters) ([]*Order, Metadata, e
 
 	var totalRecords int
 	orders := []*Order{}
+	sortKeys := []string{}
 
 	for rows.Next() {
 		var o Order
+		var sortKey string
 		err := rows.Scan(
 			&totalRecords,
 			&o.ID,
@@ -192,19 +227,200 @@ func (m OrderModel) GetAll(userID int64, filters Filters) ([]*Order, Metadata, e
 			&o.CreatedAt,
 			&o.UpdatedAt,
 			&o.Version,
+			&sortKey,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
-		// Load items
+		orders = append(orders, &o)
+		sortKeys = append(sortKeys, sortKey)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	nextCursor := ""
+	if len(orders) > filters.PageSize {
+		orders = orders[:filters.PageSize]
+		nextCursor = encodeCursor(filters.Sort, sortKeys[len(orders)-1], orders[len(orders)-1].ID)
+	}
+
+	// Load items
+	for _, o := range orders {
 		items, err := m.GetItems(o.ID)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 		o.Items = items
+	}
 
-		orders = append(orders, &o)
+	metadata := filters.metadata(totalRecords, nextCursor)
+	return orders, metadata, nil
+}
+
+// AdminOrderSearch holds the optional criteria support staff can search orders by.
+// Zero values are ignored.
+type AdminOrderSearch struct {
+	UserID        int64
+	Status        string
+	PaymentStatus string
+	ProductName   string
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+}
+
+// GetForAdmin fetches an order regardless of which user placed it.
+func (o OrderModel) GetForAdmin(id int64) (*Order, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT id, user_id, total_amount, currency, status, payment_status,
+	  shipping_address, created_at, updated_at, version
+	FROM orders
+	WHERE id = $1`
+
+	var order Order
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := o.DB.QueryRowContext(ctx, query, id).Scan(
+		&order.ID,
+		&order.UserID,
+		&order.TotalAmount,
+		&order.Currency,
+		&order.Status,
+		&order.PaymentStatus,
+		&order.ShippingAddress,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&order.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	items, err := o.GetItems(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	order.Items = items
+
+	return &order, nil
+}
+
+// GetDeliveredOrderForProduct returns the ID of the user's most recent delivered order
+// that contains the product. It returns ErrRecordNotFound if the user has never
+// received the product.
+func (o OrderModel) GetDeliveredOrderForProduct(userID, productID int64) (int64, error) {
+	if userID < 1 || productID < 1 {
+		return 0, ErrRecordNotFound
+	}
+
+	query := `
+	SELECT o.id
+	FROM orders o
+	WHERE o.user_id = $1 AND o.status = $3
+	AND EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.product_id = $2)
+	ORDER BY o.id DESC
+	LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var orderID int64
+	err := o.DB.QueryRowContext(ctx, query, userID, productID, StatusDelivered).Scan(&orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return orderID, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetAllForAdmin lists orders across all users matching the search criteria. The
+// product name matches anywhere in an item's name, taking % and _ literally.
+func (o OrderModel) GetAllForAdmin(search AdminOrderSearch, filters Filters) ([]*Order, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, user_id, total_amount, currency, status, payment_status,
+               shipping_address, created_at, updated_at, version
+        FROM orders
+        WHERE (user_id = $1 OR $1 = 0)
+        AND (status = $2 OR $2 = '')
+        AND (payment_status = $3 OR $3 = '')
+        AND ($4 = '' OR EXISTS (
+            SELECT 1 FROM order_items oi
+            WHERE oi.order_id = orders.id AND oi.product_name ILIKE '%%' || $4 || '%%' ESCAPE '\'))
+        AND (created_at >= $5 OR $5 IS NULL)
+        AND (created_at < $6 OR $6 IS NULL)
+        ORDER BY %s %s, id ASC
+        LIMIT $7 OFFSET $8`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []interface{}{
+		search.UserID,
+		search.Status,
+		search.PaymentStatus,
+		likeEscaper.Replace(strings.TrimSpace(search.ProductName)),
+		search.CreatedFrom,
+		search.CreatedTo,
+		filters.limit(),
+		filters.offset(),
+	}
+
+	rows, err := o.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	orders := []*Order{}
+
+	for rows.Next() {
+		var order Order
+		err := rows.Scan(
+			&totalRecords,
+			&order.ID,
+			&order.UserID,
+			&order.TotalAmount,
+			&order.Currency,
+			&order.Status,
+			&order.PaymentStatus,
+			&order.ShippingAddress,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+			&order.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		items, err := o.GetItems(order.ID)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		order.Items = items
+
+		orders = append(orders, &order)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -215,9 +431,63 @@ func (m OrderModel) GetAll(userID int64, filters Filters) ([]*Order, Metadata, e
 	return orders, metadata, nil
 }
 
+// UpdateStatusForAdmin changes the order status on behalf of an admin and records the
+// change, together with the reason given, in the audit log. Both writes share one
+// transaction so a status change can never go unaudited.
+func (o OrderModel) UpdateStatusForAdmin(order *Order, status string, actorID int64, reason string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE orders
+    SET status = $1, updated_at = NOW(), version = version + 1
+    WHERE id = $2 AND version = $3
+    RETURNING version, updated_at`
+
+	err = tx.QueryRowContext(ctx, query, status, order.ID, order.Version).Scan(
+		&order.Version,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = insertAuditEntryTx(ctx, tx, &AuditEntry{
+		OrderID: order.ID,
+		ActorID: actorID,
+		Action:  AuditActionStatusChanged,
+		Reason:  reason,
+		Details: map[string]string{
+			"from": order.Status,
+			"to":   status,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	order.Status = status
+	return nil
+}
+
 func (o OrderModel) GetItems(orderID int64) ([]OrderItem, error) {
 	query := `
-		SELECT id, order_id, product_id, product_name, product_image_url, unit_price,
+		SELECT id, order_id, product_id, variant_id, seller_id, product_name, product_image_url, unit_price,
 		 quantity, subtotal, created_at, updated_at		
 		FROM order_items
 		WHERE order_id = $1
@@ -240,6 +510,8 @@ func (o OrderModel) GetItems(orderID int64) ([]OrderItem, error) {
 			&item.ID,
 			&item.OrderID,
 			&item.ProductID,
+			&item.VariantID,
+			&item.SellerID,
 			&item.ProductName,
 			&item.ProductImageURL,
 			&item.UnitPrice,
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	AuditActionViewed        = "order.viewed"
+	AuditActionStatusChanged = "order.status_changed"
+	AuditActionNoteAdded     = "order.note_added"
+)
+
+// AuditEntry records a single action taken by an admin against an order.
+type AuditEntry struct {
+	ID        int64             `json:"id"`
+	OrderID   int64             `json:"order_id"`
+	ActorID   int64             `json:"actor_id"`
+	Action    string            `json:"action"`
+	Reason    string            `json:"reason,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func (m AuditModel) Insert(entry *AuditEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertAuditEntryTx(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertAuditEntryTx writes the audit row inside the caller's transaction, so the
+// action and its audit record are committed (or rolled back) together.
+func insertAuditEntryTx(ctx context.Context, tx *sql.Tx, entry *AuditEntry) error {
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return err
+	}
+	if entry.Details == nil {
+		details = []byte("{}")
+	}
+
+	query := `
+        INSERT INTO order_audit_log (order_id, actor_id, action, reason, details)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at`
+
+	return tx.QueryRowContext(ctx, query,
+		entry.OrderID,
+		entry.ActorID,
+		entry.Action,
+		entry.Reason,
+		details,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+func (m AuditModel) GetAllForOrder(orderID int64) ([]*AuditEntry, error) {
+	query := `
+        SELECT id, order_id, actor_id, action, reason, details, created_at
+        FROM order_audit_log
+        WHERE order_id = $1
+        ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*AuditEntry{}
+
+	for rows.Next() {
+		var entry AuditEntry
+		var details []byte
+
+		err := rows.Scan(
+			&entry.ID,
+			&entry.OrderID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.Reason,
+			&details,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	EventTypeStatus   = "status"
+	EventTypePayment  = "payment"
+	EventTypeShipment = "shipment"
+)
+
+// OrderEvent is a change to an order that clients can follow live. Events are written
+// by a trigger on the orders table, and their IDs double as Server-Sent Event IDs.
+type OrderEvent struct {
+	ID        int64           `json:"id"`
+	OrderID   int64           `json:"order_id"`
+	UserID    int64           `json:"-"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type OrderEventModel struct {
+	DB *sql.DB
+}
+
+func (m OrderEventModel) Get(id int64) (*OrderEvent, error) {
+	query := `
+        SELECT id, order_id, user_id, type, data, created_at
+        FROM order_events
+        WHERE id = $1`
+
+	var event OrderEvent
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&event.ID,
+		&event.OrderID,
+		&event.UserID,
+		&event.Type,
+		&event.Data,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &event, nil
+}
+
+// OrderEventBatchSize is the most events GetSince returns at a time. Callers that need
+// every event page through them until a batch comes back short.
+const OrderEventBatchSize = 500
+
+// GetSince returns up to OrderEventBatchSize of a user's events with an ID greater
+// than afterID, oldest first. If orderID is non-zero only that order's events are
+// returned.
+func (m OrderEventModel) GetSince(userID, orderID, afterID int64) ([]*OrderEvent, error) {
+	query := `
+        SELECT id, order_id, user_id, type, data, created_at
+        FROM order_events
+        WHERE user_id = $1 AND (order_id = $2 OR $2 = 0) AND id > $3
+        ORDER BY id
+        LIMIT $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, orderID, afterID, OrderEventBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*OrderEvent{}
+
+	for rows.Next() {
+		var event OrderEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.OrderID,
+			&event.UserID,
+			&event.Type,
+			&event.Data,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
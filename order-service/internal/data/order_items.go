@@ -15,6 +15,8 @@ type OrderItem struct {
 	ID              int64     `json:"id"`
 	OrderID         int64     `json:"-"`
 	ProductID       int64     `json:"product_id"`
+	VariantID       *int64    `json:"variant_id,omitempty"`
+	SellerID        int64     `json:"seller_id"`
 	ProductName     string    `json:"product_name"`
 	ProductImageURL *string   `json:"product_image_url,omitempty"`
 	UnitPrice       float64   `json:"unit_price"`
@@ -31,13 +33,15 @@ type OrderItemModel struct {
 func (o OrderItemModel) Insert(item *OrderItem) error {
 	query := `
         INSERT INTO order_items (
-		 order_id, product_id, product_name, product_image_url, unit_price, quantity
-		 ) VALUES ($1, $2, $3, $4, $5, $6)
+		 order_id, product_id, variant_id, seller_id, product_name, product_image_url, unit_price, quantity
+		 ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id, subtotal, created_at, updated_at`
 
 	args := []interface{}{
 		item.OrderID,
 		item.ProductID,
+		item.VariantID,
+		item.SellerID,
 		item.ProductName,
 		item.ProductImageURL,
 		item.UnitPrice,
@@ -61,7 +65,7 @@ func (m OrderItemModel) Get(id, orderID, userID int64) (*OrderItem, error) {
 	}
 
 	query := `
-        SELECT oi.id, oi.order_id, oi.product_id, oi.product_name, oi.product_image_url,
+        SELECT oi.id, oi.order_id, oi.product_id, oi.variant_id, oi.seller_id, oi.product_name, oi.product_image_url,
                oi.unit_price, oi.quantity, oi.subtotal, oi.created_at, oi.updated_at,
                o.user_id
         FROM order_items oi
@@ -78,6 +82,8 @@ func (m OrderItemModel) Get(id, orderID, userID int64) (*OrderItem, error) {
 		&item.ID,
 		&item.OrderID,
 		&item.ProductID,
+		&item.VariantID,
+		&item.SellerID,
 		&item.ProductName,
 		&item.ProductImageURL,
 		&item.UnitPrice,
@@ -154,12 +160,98 @@ func (o OrderItemModel) Delete(id, orderID, userID int64) error {
 	return nil
 }
 
+// GetUnattributedProducts returns up to limit IDs, in order, of the products after
+// afterID that have order items with no known seller.
+func (o OrderItemModel) GetUnattributedProducts(afterID int64, limit int) ([]int64, error) {
+	query := `
+        SELECT DISTINCT product_id
+        FROM order_items
+        WHERE seller_id = 0 AND product_id > $1
+        ORDER BY product_id
+        LIMIT $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := o.DB.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	productIDs := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		productIDs = append(productIDs, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return productIDs, nil
+}
+
+// AssignSeller attributes the product's order items that have no known seller to
+// sellerID, and returns how many there were. The items are updated one at a time, so
+// that the seller sales trigger counts each order for the seller exactly once.
+func (o OrderItemModel) AssignSeller(productID, sellerID int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := o.DB.QueryContext(ctx, `
+        SELECT id FROM order_items
+        WHERE product_id = $1 AND seller_id = 0
+        ORDER BY id`, productID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	assigned := 0
+	for _, id := range ids {
+		result, err := o.DB.ExecContext(ctx, `
+            UPDATE order_items SET seller_id = $2
+            WHERE id = $1 AND seller_id = 0`, id, sellerID)
+		if err != nil {
+			return assigned, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return assigned, err
+		}
+		assigned += int(n)
+	}
+
+	return assigned, nil
+}
+
 func ValidateOrderItem(v *validator.Validator, item *OrderItem, index int) {
 	prefix := func(field string) string {
 		return fmt.Sprintf("items[%d].%s", index, field)
 	}
 
 	v.Check(item.ProductID > 0, prefix("product_id"), "must be a positive integer")
+	if item.VariantID != nil {
+		v.Check(*item.VariantID > 0, prefix("variant_id"), "must be a positive integer")
+	}
 	v.Check(item.ProductName != "", prefix("product_name"), "must be provided")
 	v.Check(len(item.ProductName) <= 255, prefix("product_name"), "must not exceed 255 characters")
 
@@ -0,0 +1,275 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+// ErrMessageLimit is returned when a user has already sent as many messages as they
+// may in the period.
+var ErrMessageLimit = errors.New("message limit reached")
+
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+	RoleAdmin  = "admin"
+)
+
+// OrderMessage is a message in an order's thread between the buyer, the sellers of
+// the items in the order and support staff.
+type OrderMessage struct {
+	ID             int64     `json:"id"`
+	OrderID        int64     `json:"order_id"`
+	SenderID       int64     `json:"sender_id"`
+	SenderRole     string    `json:"sender_role"`
+	Body           string    `json:"body"`
+	AttachmentURLs []string  `json:"attachment_urls"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// UnreadCount is the number of unread messages in one order's thread.
+type UnreadCount struct {
+	OrderID int64 `json:"order_id"`
+	Unread  int   `json:"unread"`
+}
+
+type OrderMessageModel struct {
+	DB *sql.DB
+}
+
+// ParticipantRole reports how a user takes part in an order's thread: as the buyer,
+// as the seller of at least one item, or not at all (""). Admin access is decided by
+// the caller from the user's permissions.
+func (m OrderMessageModel) ParticipantRole(orderID, userID int64) (string, error) {
+	if orderID < 1 {
+		return "", ErrRecordNotFound
+	}
+
+	query := `
+        SELECT o.user_id = $2,
+               EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.seller_id = $2)
+        FROM orders o
+        WHERE o.id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var isBuyer, isSeller bool
+
+	err := m.DB.QueryRowContext(ctx, query, orderID, userID).Scan(&isBuyer, &isSeller)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	switch {
+	case isBuyer:
+		return RoleBuyer, nil
+	case isSeller:
+		return RoleSeller, nil
+	default:
+		return "", nil
+	}
+}
+
+// Insert adds a message to its thread. If limit is greater than zero, it returns
+// ErrMessageLimit instead if the sender has already sent limit messages, across all
+// orders, since the given time. The count and the insert hold a lock on the sender,
+// so that concurrent messages can't together go over the limit.
+func (m OrderMessageModel) Insert(message *OrderMessage, limit int, since time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if limit > 0 {
+		// Released when the transaction ends. Senders whose IDs hash alike only wait
+		// for each other briefly.
+		_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('order_messages'), hashtext($1::text))`, message.SenderID)
+		if err != nil {
+			return err
+		}
+
+		var sent int
+		err = tx.QueryRowContext(ctx, `
+        SELECT count(*)
+        FROM order_messages
+        WHERE sender_id = $1 AND created_at >= $2`, message.SenderID, since).Scan(&sent)
+		if err != nil {
+			return err
+		}
+		if sent >= limit {
+			return ErrMessageLimit
+		}
+	}
+
+	query := `
+        INSERT INTO order_messages (order_id, sender_id, sender_role, body, attachment_urls)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at`
+
+	args := []interface{}{
+		message.OrderID,
+		message.SenderID,
+		message.SenderRole,
+		message.Body,
+		pq.Array(message.AttachmentURLs),
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&message.ID, &message.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m OrderMessageModel) GetAllForOrder(orderID int64, filters Filters) ([]*OrderMessage, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), id, order_id, sender_id, sender_role, body, attachment_urls, created_at
+        FROM order_messages
+        WHERE order_id = $1
+        ORDER BY %s %s
+        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, orderID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	var totalRecords int
+	messages := []*OrderMessage{}
+
+	for rows.Next() {
+		var message OrderMessage
+		err := rows.Scan(
+			&totalRecords,
+			&message.ID,
+			&message.OrderID,
+			&message.SenderID,
+			&message.SenderRole,
+			&message.Body,
+			pq.Array(&message.AttachmentURLs),
+			&message.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		messages = append(messages, &message)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return messages, metadata, nil
+}
+
+// UnreadForOrder returns how many messages in an order's thread the user hasn't read.
+// Their own messages never count.
+func (m OrderMessageModel) UnreadForOrder(orderID, userID int64) (int, error) {
+	query := `
+        SELECT count(*)
+        FROM order_messages msg
+        LEFT JOIN order_message_reads r ON r.order_id = msg.order_id AND r.user_id = $2
+        WHERE msg.order_id = $1
+        AND msg.sender_id <> $2
+        AND msg.id > coalesce(r.last_read_message_id, 0)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var unread int
+	err := m.DB.QueryRowContext(ctx, query, orderID, userID).Scan(&unread)
+	return unread, err
+}
+
+// UnreadForUser returns unread counts for every thread the user takes part in as a
+// buyer or seller, leaving out threads with nothing unread.
+func (m OrderMessageModel) UnreadForUser(userID int64) ([]UnreadCount, error) {
+	query := `
+        SELECT msg.order_id, count(*)
+        FROM order_messages msg
+        JOIN orders o ON o.id = msg.order_id
+        LEFT JOIN order_message_reads r ON r.order_id = msg.order_id AND r.user_id = $1
+        WHERE (o.user_id = $1 OR EXISTS (
+            SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND oi.seller_id = $1))
+        AND msg.sender_id <> $1
+        AND msg.id > coalesce(r.last_read_message_id, 0)
+        GROUP BY msg.order_id
+        ORDER BY msg.order_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []UnreadCount{}
+
+	for rows.Next() {
+		var count UnreadCount
+		if err := rows.Scan(&count.OrderID, &count.Unread); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// MarkRead records that the user has seen every message in the thread up to and
+// including messageID. It never moves the marker backwards.
+func (m OrderMessageModel) MarkRead(orderID, userID, messageID int64) error {
+	query := `
+        INSERT INTO order_message_reads (order_id, user_id, last_read_message_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (order_id, user_id) DO UPDATE
+        SET last_read_message_id = GREATEST(order_message_reads.last_read_message_id, EXCLUDED.last_read_message_id),
+            updated_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, orderID, userID, messageID)
+	return err
+}
+
+func ValidateOrderMessage(v *validator.Validator, message *OrderMessage) {
+	v.Check(message.Body != "", "body", "must be provided")
+	v.Check(len(message.Body) <= 2000, "body", "must not exceed 2000 characters")
+
+	v.Check(len(message.AttachmentURLs) <= 5, "attachment_urls", "must not contain more than 5 URLs")
+	v.Check(validator.Unique(message.AttachmentURLs), "attachment_urls", "must not contain duplicate values")
+	for i, u := range message.AttachmentURLs {
+		key := fmt.Sprintf("attachment_urls[%d]", i)
+		v.Check(len(u) <= 1000, key, "must not exceed 1000 characters")
+		v.Check(validator.IsURL(u), key, "must be a valid URL")
+	}
+}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+)
+
+// OrderNote is an internal note left on an order by support staff. Notes are never
+// shown to the buyer.
+type OrderNote struct {
+	ID        int64     `json:"id"`
+	OrderID   int64     `json:"order_id"`
+	AuthorID  int64     `json:"author_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrderNoteModel struct {
+	DB *sql.DB
+}
+
+// Insert adds the note and its audit entry in a single transaction.
+func (m OrderNoteModel) Insert(note *OrderNote) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO order_notes (order_id, author_id, body)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query, note.OrderID, note.AuthorID, note.Body).Scan(
+		&note.ID,
+		&note.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = insertAuditEntryTx(ctx, tx, &AuditEntry{
+		OrderID: note.OrderID,
+		ActorID: note.AuthorID,
+		Action:  AuditActionNoteAdded,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m OrderNoteModel) GetAllForOrder(orderID int64) ([]*OrderNote, error) {
+	query := `
+        SELECT id, order_id, author_id, body, created_at
+        FROM order_notes
+        WHERE order_id = $1
+        ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []*OrderNote{}
+
+	for rows.Next() {
+		var note OrderNote
+		err := rows.Scan(
+			&note.ID,
+			&note.OrderID,
+			&note.AuthorID,
+			&note.Body,
+			&note.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, &note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+func ValidateOrderNote(v *validator.Validator, note *OrderNote) {
+	v.Check(note.Body != "", "body", "must be provided")
+	v.Check(len(note.Body) <= 5000, "body", "must not exceed 5000 characters")
+}
@@ -0,0 +1,31 @@
+package data
+
+// Minimal Product struct - just what we need from product-service
+type Product struct {
+	ID       int64            `json:"id"`
+	UserID   int64            `json:"user_id"`
+	Name     string           `json:"name"`
+	Price    float64          `json:"price"`
+	ImageURL string           `json:"image_url"`
+	Stock    int32            `json:"stock"`
+	Variants []ProductVariant `json:"variants"`
+}
+
+// ProductVariant is the part of a product-service variant we need. A nil Price means
+// the variant sells at the product's price.
+type ProductVariant struct {
+	ID       int64    `json:"id"`
+	Price    *float64 `json:"price"`
+	ImageURL string   `json:"image_url"`
+	Stock    int32    `json:"stock"`
+}
+
+// Variant returns the product's variant with the given ID, or nil if it has none.
+func (p *Product) Variant(id int64) *ProductVariant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/validator"
+)
+
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// SalesBucket holds a seller's totals for one day, week or month.
+type SalesBucket struct {
+	PeriodStart string  `json:"period_start"`
+	Revenue     float64 `json:"revenue"`
+	Units       int     `json:"units"`
+	Orders      int     `json:"orders"`
+}
+
+// TopProduct is a product ranked by revenue over the requested range.
+type TopProduct struct {
+	ProductID   int64   `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Revenue     float64 `json:"revenue"`
+	Units       int     `json:"units"`
+}
+
+// SalesSummary holds the totals and rates over the whole requested range. Revenue
+// and units exclude cancelled orders.
+type SalesSummary struct {
+	Revenue           float64 `json:"revenue"`
+	Units             int     `json:"units"`
+	Orders            int     `json:"orders"`
+	CancelledOrders   int     `json:"cancelled_orders"`
+	ReturnedOrders    int     `json:"returned_orders"`
+	AverageOrderValue float64 `json:"average_order_value"`
+	CancellationRate  float64 `json:"cancellation_rate"`
+	ReturnRate        float64 `json:"return_rate"`
+}
+
+type SellerAnalytics struct {
+	From        string        `json:"from"`
+	To          string        `json:"to"`
+	Granularity string        `json:"granularity"`
+	Summary     SalesSummary  `json:"summary"`
+	Series      []SalesBucket `json:"series"`
+	TopProducts []TopProduct  `json:"top_products"`
+}
+
+// SellerAnalyticsModel reads from the seller_sales_daily and
+// seller_product_sales_daily tables, which triggers on orders and order_items keep up
+// to date as orders change. Queries never touch the orders tables directly.
+type SellerAnalyticsModel struct {
+	DB *sql.DB
+}
+
+// Get returns the analytics for a seller between from and to (both inclusive).
+func (m SellerAnalyticsModel) Get(sellerID int64, from, to time.Time, granularity string, topN int) (*SellerAnalytics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	analytics := &SellerAnalytics{
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		Granularity: granularity,
+		Series:      []SalesBucket{},
+		TopProducts: []TopProduct{},
+	}
+
+	// Summary over the whole range.
+	query := `
+        SELECT coalesce(sum(revenue), 0), coalesce(sum(units), 0), coalesce(sum(orders), 0),
+               coalesce(sum(cancelled_orders), 0), coalesce(sum(returned_orders), 0)
+        FROM seller_sales_daily
+        WHERE seller_id = $1 AND day BETWEEN $2 AND $3`
+
+	s := &analytics.Summary
+	err := m.DB.QueryRowContext(ctx, query, sellerID, from, to).Scan(
+		&s.Revenue,
+		&s.Units,
+		&s.Orders,
+		&s.CancelledOrders,
+		&s.ReturnedOrders,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if completed := s.Orders - s.CancelledOrders; completed > 0 {
+		s.AverageOrderValue = s.Revenue / float64(completed)
+	}
+	if s.Orders > 0 {
+		s.CancellationRate = float64(s.CancelledOrders) / float64(s.Orders)
+		s.ReturnRate = float64(s.ReturnedOrders) / float64(s.Orders)
+	}
+
+	// Time series, bucketed by the requested granularity.
+	query = `
+        SELECT to_char(date_trunc($4, day::timestamp), 'YYYY-MM-DD') AS period,
+               sum(revenue), sum(units), sum(orders)
+        FROM seller_sales_daily
+        WHERE seller_id = $1 AND day BETWEEN $2 AND $3
+        GROUP BY period
+        ORDER BY period`
+
+	rows, err := m.DB.QueryContext(ctx, query, sellerID, from, to, granularity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket SalesBucket
+		err := rows.Scan(&bucket.PeriodStart, &bucket.Revenue, &bucket.Units, &bucket.Orders)
+		if err != nil {
+			return nil, err
+		}
+		analytics.Series = append(analytics.Series, bucket)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Top products by revenue.
+	query = `
+        SELECT product_id, max(product_name), sum(revenue) AS total_revenue, sum(units)
+        FROM seller_product_sales_daily
+        WHERE seller_id = $1 AND day BETWEEN $2 AND $3
+        GROUP BY product_id
+        HAVING sum(units) > 0
+        ORDER BY total_revenue DESC, product_id ASC
+        LIMIT $4`
+
+	rows, err = m.DB.QueryContext(ctx, query, sellerID, from, to, topN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product TopProduct
+		err := rows.Scan(&product.ProductID, &product.ProductName, &product.Revenue, &product.Units)
+		if err != nil {
+			return nil, err
+		}
+		analytics.TopProducts = append(analytics.TopProducts, product)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return analytics, nil
+}
+
+func ValidateAnalyticsRange(v *validator.Validator, from, to time.Time, granularity string, topN int) {
+	v.Check(!to.Before(from), "to", "must not be before from")
+	v.Check(to.Sub(from) <= 2*366*24*time.Hour, "to", "range must not exceed two years")
+	v.Check(validator.In(granularity, GranularityDay, GranularityWeek, GranularityMonth),
+		"granularity", "must be one of day, week or month")
+	v.Check(topN > 0, "top", "must be greater than zero")
+	v.Check(topN <= 50, "top", "must be a maximum of 50")
+}
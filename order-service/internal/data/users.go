@@ -2,10 +2,11 @@ package data
 
 // Minimal User struct - just what we need from user-service
 type User struct {
-	ID        int64  `json:"id"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	Activated bool   `json:"activated"`
+	ID          int64       `json:"id"`
+	Email       string      `json:"email"`
+	Name        string      `json:"name"`
+	Activated   bool        `json:"activated"`
+	Permissions Permissions `json:"permissions"`
 }
 
 // AnonymousUser represents an unauthenticated user
@@ -15,3 +16,16 @@ var AnonymousUser = &User{}
 func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
+
+// Permissions holds the permission codes user-service returns for a user.
+type Permissions []string
+
+// Include checks whether the Permissions slice contains a specific permission code.
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] {
+			return true
+		}
+	}
+	return false
+}
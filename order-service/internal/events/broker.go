@@ -0,0 +1,162 @@
+// internal/events/broker.go
+package events
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/order-service/internal/jsonlog"
+	"github.com/lib/pq"
+)
+
+// channel is the Postgres NOTIFY channel the order_events trigger publishes on.
+const channel = "order_events"
+
+// subscriberBuffer is how many events a slow client may fall behind before it is
+// disconnected. Clients resume from their Last-Event-ID when they reconnect.
+const subscriberBuffer = 32
+
+type subscriber struct {
+	userID  int64
+	orderID int64
+	ch      chan *data.OrderEvent
+}
+
+// Broker fans order events out to the streams that are interested in them. Events
+// arrive from Postgres LISTEN/NOTIFY, so every replica sees changes made by any other.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[*subscriber]struct{}
+	closed bool
+	done   chan struct{}
+	model  data.OrderEventModel
+	logger *jsonlog.Logger
+}
+
+// NewBroker creates a broker which loads notified events using the given model.
+func NewBroker(model data.OrderEventModel, logger *jsonlog.Logger) *Broker {
+	return &Broker{
+		subs:   make(map[*subscriber]struct{}),
+		done:   make(chan struct{}),
+		model:  model,
+		logger: logger,
+	}
+}
+
+// Subscribe registers interest in a user's events. If orderID is non-zero only that
+// order's events are delivered. The returned channel is closed when the broker shuts
+// down or the subscriber falls too far behind; call the returned function to
+// unsubscribe.
+func (b *Broker) Subscribe(userID, orderID int64) (<-chan *data.OrderEvent, func()) {
+	s := &subscriber{
+		userID:  userID,
+		orderID: orderID,
+		ch:      make(chan *data.OrderEvent, subscriberBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(s.ch)
+		return s.ch, func() {}
+	}
+	b.subs[s] = struct{}{}
+
+	return s.ch, func() { b.remove(s) }
+}
+
+func (b *Broker) remove(s *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Publish delivers an event to every matching subscriber without blocking.
+func (b *Broker) Publish(event *data.OrderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subs {
+		if s.userID != event.UserID || (s.orderID != 0 && s.orderID != event.OrderID) {
+			continue
+		}
+		select {
+		case s.ch <- event:
+		default:
+			delete(b.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Close disconnects every subscriber and stops Listen. It is safe to call more than
+// once.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Listen subscribes to the order_events NOTIFY channel and publishes each event until
+// Close is called.
+func (b *Broker) Listen(dsn string) error {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			b.logger.PrintError(err, map[string]string{"component": "order_events_listener"})
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-b.done:
+			return nil
+
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established; anything
+			// sent in between is picked up by clients resuming with Last-Event-ID.
+			if n == nil {
+				continue
+			}
+
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				b.logger.PrintError(err, map[string]string{"payload": n.Extra})
+				continue
+			}
+
+			event, err := b.model.Get(id)
+			if err != nil {
+				b.logger.PrintError(err, map[string]string{"event_id": n.Extra})
+				continue
+			}
+
+			b.Publish(event)
+
+		case <-time.After(90 * time.Second):
+			// Ping periodically so a silently dropped connection is noticed.
+			go listener.Ping()
+		}
+	}
+}
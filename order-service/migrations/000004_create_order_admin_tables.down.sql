@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_audit_log;
+DROP TABLE IF EXISTS order_notes;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS order_notes (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    author_id BIGINT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_notes_order_id ON order_notes(order_id);
+
+-- Audit rows deliberately don't reference orders(id) so the trail survives deletion.
+CREATE TABLE IF NOT EXISTS order_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    actor_id BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_audit_log_order_id ON order_audit_log(order_id);
+CREATE INDEX idx_order_audit_log_actor_id ON order_audit_log(actor_id);
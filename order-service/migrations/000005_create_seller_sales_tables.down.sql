@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS trg_seller_sales_orders_delete ON orders;
+DROP TRIGGER IF EXISTS trg_seller_sales_orders_update ON orders;
+DROP TRIGGER IF EXISTS trg_seller_sales_order_items ON order_items;
+DROP FUNCTION IF EXISTS seller_sales_orders_trigger();
+DROP FUNCTION IF EXISTS seller_sales_order_items_trigger();
+DROP FUNCTION IF EXISTS seller_sales_add(BIGINT, DATE, BIGINT, TEXT, DECIMAL, INTEGER, INTEGER, INTEGER, INTEGER);
+DROP TABLE IF EXISTS seller_product_sales_daily;
+DROP TABLE IF EXISTS seller_sales_daily;
+DROP INDEX IF EXISTS idx_order_items_seller_id;
+ALTER TABLE order_items DROP COLUMN IF EXISTS seller_id;
@@ -0,0 +1,190 @@
+-- The seller is resolved from product-service when an item is added. Items created
+-- before this migration, or while product-service couldn't be reached, start at 0, and
+-- order-service's seller backfill attributes them later. Items at 0 are left out of
+-- the aggregates until then.
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS seller_id BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_order_items_seller_id ON order_items(seller_id);
+
+-- Daily per-seller totals. Revenue and units exclude cancelled orders; orders counts
+-- every order containing at least one of the seller's items.
+CREATE TABLE IF NOT EXISTS seller_sales_daily (
+    seller_id BIGINT NOT NULL,
+    day DATE NOT NULL,
+    revenue DECIMAL(14,2) NOT NULL DEFAULT 0,
+    units INTEGER NOT NULL DEFAULT 0,
+    orders INTEGER NOT NULL DEFAULT 0,
+    cancelled_orders INTEGER NOT NULL DEFAULT 0,
+    returned_orders INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (seller_id, day)
+);
+
+-- Daily per-product totals used for the top products ranking.
+CREATE TABLE IF NOT EXISTS seller_product_sales_daily (
+    seller_id BIGINT NOT NULL,
+    day DATE NOT NULL,
+    product_id BIGINT NOT NULL,
+    product_name TEXT NOT NULL DEFAULT '',
+    revenue DECIMAL(14,2) NOT NULL DEFAULT 0,
+    units INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (seller_id, day, product_id)
+);
+
+-- seller_sales_add applies a delta to both aggregate tables.
+CREATE OR REPLACE FUNCTION seller_sales_add(
+    p_seller_id BIGINT, p_day DATE, p_product_id BIGINT, p_product_name TEXT,
+    p_revenue DECIMAL, p_units INTEGER,
+    p_orders INTEGER, p_cancelled INTEGER, p_returned INTEGER
+) RETURNS VOID AS $$
+BEGIN
+    INSERT INTO seller_sales_daily AS s
+        (seller_id, day, revenue, units, orders, cancelled_orders, returned_orders)
+    VALUES (p_seller_id, p_day, p_revenue, p_units, p_orders, p_cancelled, p_returned)
+    ON CONFLICT (seller_id, day) DO UPDATE SET
+        revenue = s.revenue + EXCLUDED.revenue,
+        units = s.units + EXCLUDED.units,
+        orders = s.orders + EXCLUDED.orders,
+        cancelled_orders = s.cancelled_orders + EXCLUDED.cancelled_orders,
+        returned_orders = s.returned_orders + EXCLUDED.returned_orders;
+
+    IF p_product_id IS NOT NULL AND (p_revenue <> 0 OR p_units <> 0) THEN
+        INSERT INTO seller_product_sales_daily AS p
+            (seller_id, day, product_id, product_name, revenue, units)
+        VALUES (p_seller_id, p_day, p_product_id, p_product_name, p_revenue, p_units)
+        ON CONFLICT (seller_id, day, product_id) DO UPDATE SET
+            product_name = EXCLUDED.product_name,
+            revenue = p.revenue + EXCLUDED.revenue,
+            units = p.units + EXCLUDED.units;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Keep the aggregates in step with order_items. When the parent order has already
+-- gone (cascading delete) the orders trigger below has accounted for it.
+CREATE OR REPLACE FUNCTION seller_sales_order_items_trigger() RETURNS TRIGGER AS $$
+DECLARE
+    o RECORD;
+    remaining INTEGER;
+    order_delta INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        SELECT status, payment_status, (created_at AT TIME ZONE 'UTC')::date AS day
+        INTO o FROM orders WHERE id = OLD.order_id;
+    ELSE
+        SELECT status, payment_status, (created_at AT TIME ZONE 'UTC')::date AS day
+        INTO o FROM orders WHERE id = NEW.order_id;
+    END IF;
+
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.seller_id <> 0 THEN
+        SELECT count(*) INTO remaining FROM order_items
+        WHERE order_id = OLD.order_id AND seller_id = OLD.seller_id;
+        -- OLD is NULL on INSERT and NEW on DELETE, so these also hold for the item
+        -- leaving or joining a seller when its seller_id is set.
+        order_delta := CASE WHEN (TG_OP = 'DELETE' OR NEW.seller_id <> OLD.seller_id) AND remaining = 0 THEN -1 ELSE 0 END;
+
+        PERFORM seller_sales_add(OLD.seller_id, o.day, OLD.product_id, OLD.product_name,
+            CASE WHEN o.status = 'cancelled' THEN 0 ELSE -OLD.subtotal END,
+            CASE WHEN o.status = 'cancelled' THEN 0 ELSE -OLD.quantity END,
+            order_delta,
+            CASE WHEN o.status = 'cancelled' THEN order_delta ELSE 0 END,
+            CASE WHEN o.payment_status = 'refunded' THEN order_delta ELSE 0 END);
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.seller_id <> 0 THEN
+        SELECT count(*) INTO remaining FROM order_items
+        WHERE order_id = NEW.order_id AND seller_id = NEW.seller_id;
+        order_delta := CASE WHEN (TG_OP = 'INSERT' OR NEW.seller_id <> OLD.seller_id) AND remaining = 1 THEN 1 ELSE 0 END;
+
+        PERFORM seller_sales_add(NEW.seller_id, o.day, NEW.product_id, NEW.product_name,
+            CASE WHEN o.status = 'cancelled' THEN 0 ELSE NEW.subtotal END,
+            CASE WHEN o.status = 'cancelled' THEN 0 ELSE NEW.quantity END,
+            order_delta,
+            CASE WHEN o.status = 'cancelled' THEN order_delta ELSE 0 END,
+            CASE WHEN o.payment_status = 'refunded' THEN order_delta ELSE 0 END);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_seller_sales_order_items
+AFTER INSERT OR UPDATE OR DELETE ON order_items
+FOR EACH ROW EXECUTE FUNCTION seller_sales_order_items_trigger();
+
+-- Status and payment changes move revenue in and out of the totals and adjust the
+-- cancellation and return counters for every seller in the order.
+CREATE OR REPLACE FUNCTION seller_sales_orders_trigger() RETURNS TRIGGER AS $$
+DECLARE
+    order_day DATE;
+    item RECORD;
+    seller RECORD;
+    revenue_sign INTEGER := 0;
+    cancelled_delta INTEGER := 0;
+    returned_delta INTEGER := 0;
+BEGIN
+    order_day := (OLD.created_at AT TIME ZONE 'UTC')::date;
+
+    IF TG_OP = 'DELETE' THEN
+        revenue_sign := CASE WHEN OLD.status = 'cancelled' THEN 0 ELSE -1 END;
+        cancelled_delta := CASE WHEN OLD.status = 'cancelled' THEN -1 ELSE 0 END;
+        returned_delta := CASE WHEN OLD.payment_status = 'refunded' THEN -1 ELSE 0 END;
+    ELSE
+        IF OLD.status <> 'cancelled' AND NEW.status = 'cancelled' THEN
+            revenue_sign := -1;
+            cancelled_delta := 1;
+        ELSIF OLD.status = 'cancelled' AND NEW.status <> 'cancelled' THEN
+            revenue_sign := 1;
+            cancelled_delta := -1;
+        END IF;
+
+        IF OLD.payment_status <> 'refunded' AND NEW.payment_status = 'refunded' THEN
+            returned_delta := 1;
+        ELSIF OLD.payment_status = 'refunded' AND NEW.payment_status <> 'refunded' THEN
+            returned_delta := -1;
+        END IF;
+    END IF;
+
+    IF revenue_sign <> 0 THEN
+        FOR item IN
+            SELECT seller_id, product_id, max(product_name) AS product_name,
+                   sum(subtotal) AS revenue, sum(quantity)::int AS units
+            FROM order_items
+            WHERE order_id = OLD.id AND seller_id <> 0
+            GROUP BY seller_id, product_id
+        LOOP
+            PERFORM seller_sales_add(item.seller_id, order_day, item.product_id, item.product_name,
+                revenue_sign * item.revenue, revenue_sign * item.units, 0, 0, 0);
+        END LOOP;
+    END IF;
+
+    IF TG_OP = 'DELETE' OR cancelled_delta <> 0 OR returned_delta <> 0 THEN
+        FOR seller IN
+            SELECT DISTINCT seller_id FROM order_items
+            WHERE order_id = OLD.id AND seller_id <> 0
+        LOOP
+            PERFORM seller_sales_add(seller.seller_id, order_day, NULL, '', 0, 0,
+                CASE WHEN TG_OP = 'DELETE' THEN -1 ELSE 0 END,
+                cancelled_delta, returned_delta);
+        END LOOP;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_seller_sales_orders_update
+AFTER UPDATE OF status, payment_status ON orders
+FOR EACH ROW EXECUTE FUNCTION seller_sales_orders_trigger();
+
+-- BEFORE DELETE so the order's items are still there to be subtracted.
+CREATE TRIGGER trg_seller_sales_orders_delete
+BEFORE DELETE ON orders
+FOR EACH ROW EXECUTE FUNCTION seller_sales_orders_trigger();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_order_events_notify ON order_events;
+DROP TRIGGER IF EXISTS trg_order_events_record ON orders;
+DROP FUNCTION IF EXISTS order_events_notify();
+DROP FUNCTION IF EXISTS order_events_record();
+DROP TABLE IF EXISTS order_events;
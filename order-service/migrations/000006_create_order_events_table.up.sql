@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS order_events (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    type TEXT NOT NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_events_user_id ON order_events(user_id, id);
+CREATE INDEX idx_order_events_order_id ON order_events(order_id, id);
+
+-- Record an event whenever an order's status or payment status changes, whichever
+-- code path made the change.
+CREATE OR REPLACE FUNCTION order_events_record() RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.status IS DISTINCT FROM NEW.status THEN
+        INSERT INTO order_events (order_id, user_id, type, data)
+        VALUES (NEW.id, NEW.user_id,
+            CASE WHEN NEW.status IN ('shipped', 'delivered') THEN 'shipment' ELSE 'status' END,
+            jsonb_build_object('from', OLD.status, 'to', NEW.status));
+    END IF;
+
+    IF OLD.payment_status IS DISTINCT FROM NEW.payment_status THEN
+        INSERT INTO order_events (order_id, user_id, type, data)
+        VALUES (NEW.id, NEW.user_id, 'payment',
+            jsonb_build_object('from', OLD.payment_status, 'to', NEW.payment_status));
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_order_events_record
+AFTER UPDATE OF status, payment_status ON orders
+FOR EACH ROW EXECUTE FUNCTION order_events_record();
+
+-- Wake up every replica's listener. Only the id travels over NOTIFY; listeners load
+-- the row themselves.
+CREATE OR REPLACE FUNCTION order_events_notify() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('order_events', NEW.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_order_events_notify
+AFTER INSERT ON order_events
+FOR EACH ROW EXECUTE FUNCTION order_events_notify();
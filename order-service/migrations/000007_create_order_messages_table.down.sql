@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_message_reads;
+DROP TABLE IF EXISTS order_messages;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS order_messages (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    sender_id BIGINT NOT NULL,
+    sender_role TEXT NOT NULL,
+    body TEXT NOT NULL,
+    attachment_urls TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_messages_order_id ON order_messages(order_id, id);
+CREATE INDEX idx_order_messages_sender_created ON order_messages(sender_id, created_at);
+
+-- The newest message each participant has seen in a thread. Everything after it from
+-- someone else counts as unread.
+CREATE TABLE IF NOT EXISTS order_message_reads (
+    order_id BIGINT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    last_read_message_id BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (order_id, user_id)
+);
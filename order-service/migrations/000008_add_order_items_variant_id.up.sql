@@ -0,0 +1,4 @@
+-- The variant (size, colour, ...) of the product that was ordered, if it has variants.
+-- Variants live in product-service, so there is no foreign key.
+ALTER TABLE order_items
+ADD COLUMN IF NOT EXISTS variant_id BIGINT;
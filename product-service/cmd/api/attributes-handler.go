@@ -0,0 +1,217 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// listCategoryAttributesHandler returns the attributes products in a category can
+// have, including those defined on its ancestors.
+func (app *application) listCategoryAttributesHandler(w http.ResponseWriter, r *http.Request) {
+	category := app.categoryFromRequest(w, r)
+	if category == nil {
+		return
+	}
+
+	attributes, err := app.models.Attributes.GetForCategory(category.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"attributes": attributes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCategoryAttributeHandler(w http.ResponseWriter, r *http.Request) {
+	category := app.categoryFromRequest(w, r)
+	if category == nil {
+		return
+	}
+
+	var input struct {
+		Key     string   `json:"key"`
+		Name    string   `json:"name"`
+		Type    string   `json:"type"`
+		Options []string `json:"options"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	def := &data.AttributeDefinition{
+		CategoryID: category.ID,
+		Category:   category.Slug,
+		Key:        input.Key,
+		Name:       strings.TrimSpace(input.Name),
+		Type:       input.Type,
+		Options:    input.Options,
+	}
+
+	v := validator.New()
+
+	if data.ValidateAttributeDefinition(v, def); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Attributes.Insert(def)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAttribute):
+			v.AddError("key", "is already defined on this category, its parents or its subcategories")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/attributes/%d", def.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"attribute": def}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateAttributeHandler changes an attribute's name or options. Its key and type are
+// fixed, since products' values depend on them.
+func (app *application) updateAttributeHandler(w http.ResponseWriter, r *http.Request) {
+	def := app.attributeFromRequest(w, r)
+	if def == nil {
+		return
+	}
+
+	var input struct {
+		Name    *string  `json:"name"`
+		Options []string `json:"options"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		def.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Options != nil {
+		def.Options = input.Options
+	}
+
+	v := validator.New()
+
+	if data.ValidateAttributeDefinition(v, def); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Attributes.Update(def)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrAttributeInUse):
+			v.AddError("options", "must include every option products still use")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"attribute": def}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAttributeHandler(w http.ResponseWriter, r *http.Request) {
+	def := app.attributeFromRequest(w, r)
+	if def == nil {
+		return
+	}
+
+	err := app.models.Attributes.Delete(def)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrAttributeInUse):
+			app.errorResponse(w, r, http.StatusConflict, "products still have a value for the attribute")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "attribute deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// categoryFromRequest loads the category named by the id URL parameter, sending a
+// response and returning nil if it can't.
+func (app *application) categoryFromRequest(w http.ResponseWriter, r *http.Request) *data.Category {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return category
+}
+
+// attributeFromRequest loads the attribute named by the id URL parameter, sending a
+// response and returning nil if it can't.
+func (app *application) attributeFromRequest(w http.ResponseWriter, r *http.Request) *data.AttributeDefinition {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	def, err := app.models.Attributes.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return def
+}
+
+// productAttributeDefinitions returns the attribute definitions that apply to the
+// product's categories, for data.ValidateProduct.
+func (app *application) productAttributeDefinitions(product *data.Product) ([]*data.AttributeDefinition, error) {
+	return app.models.Attributes.GetForCategories(product.Category)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+// listBrandsHandler returns a page of brands, optionally searched by name.
+func (app *application) listBrandsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Name = strings.TrimSpace(app.readString(qs, "name", ""))
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 50, v)
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"name", "-name", "created_at", "-created_at"}
+
+	v.Check(len(input.Name) <= 100, "name", "must not exceed 100 characters")
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	brands, metadata, err := app.models.Brands.GetAll(input.Name, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"brands": brands, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showBrandHandler(w http.ResponseWriter, r *http.Request) {
+	brand := app.brandFromRequest(w, r)
+	if brand == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"brand": brand}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listBrandProductsHandler lists a brand's products. It takes the same filters,
+// sorting and paging as the main product list.
+func (app *application) listBrandProductsHandler(w http.ResponseWriter, r *http.Request) {
+	brand := app.brandFromRequest(w, r)
+	if brand == nil {
+		return
+	}
+
+	app.listProducts(w, r, data.ProductSearch{Brands: []string{brand.Slug}})
+}
+
+// createBrandHandler adds a brand. Its slug is made from its name unless one is given.
+func (app *application) createBrandHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string `json:"name"`
+		Slug        string `json:"slug"`
+		LogoURL     string `json:"logo_url"`
+		Description string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	brand := &data.Brand{
+		Name:        strings.TrimSpace(input.Name),
+		Slug:        input.Slug,
+		LogoURL:     input.LogoURL,
+		Description: input.Description,
+	}
+	if brand.Slug == "" {
+		brand.Slug = data.Slugify(brand.Name)
+	}
+
+	v := validator.New()
+
+	if data.ValidateBrand(v, brand); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Brands.Insert(brand)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSlug):
+			v.AddError("slug", "a brand with this slug already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/brands/%s", brand.Slug))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"brand": brand}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateBrandHandler(w http.ResponseWriter, r *http.Request) {
+	brand := app.brandFromRequest(w, r)
+	if brand == nil {
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Slug        *string `json:"slug"`
+		LogoURL     *string `json:"logo_url"`
+		Description *string `json:"description"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		brand.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Slug != nil {
+		brand.Slug = *input.Slug
+	}
+	if input.LogoURL != nil {
+		brand.LogoURL = *input.LogoURL
+	}
+	if input.Description != nil {
+		brand.Description = *input.Description
+	}
+
+	v := validator.New()
+
+	if data.ValidateBrand(v, brand); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Brands.Update(brand)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSlug):
+			v.AddError("slug", "a brand with this slug already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"brand": brand}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteBrandHandler(w http.ResponseWriter, r *http.Request) {
+	brand := app.brandFromRequest(w, r)
+	if brand == nil {
+		return
+	}
+
+	err := app.models.Brands.Delete(brand.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrBrandInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the brand still has products")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "brand deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// brandFromRequest loads the brand whose slug is in the URL. It writes the error
+// response itself and returns nil on failure.
+func (app *application) brandFromRequest(w http.ResponseWriter, r *http.Request) *data.Brand {
+	brand, err := app.models.Brands.GetBySlug(chi.URLParam(r, "slug"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return brand
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+)
+
+// productETag identifies a version of a product as shown to shoppers. It is weak
+// because the response is built from more than the product row.
+func productETag(product *data.Product, lastModified time.Time) string {
+	return fmt.Sprintf(`W/"%d-%d-%x"`, product.ID, product.Version, lastModified.UnixMicro())
+}
+
+// productListETag identifies a page of the product list by the versions of the
+// products on it, along with everything else in the response that can change without
+// them: the metadata, the facets and whether the search was fuzzy.
+func productListETag(products []*data.Product, now time.Time, extra envelope) (string, error) {
+	h := sha256.New()
+	for _, product := range products {
+		fmt.Fprintf(h, "%d-%d-%d\n", product.ID, product.Version, product.LastModified(now).UnixMicro())
+	}
+
+	js, err := json.Marshal(extra)
+	if err != nil {
+		return "", err
+	}
+	h.Write(js)
+
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, nil
+}
+
+// notModified sets the caching headers of a public response and reports whether the
+// client's copy is still current, in which case it has been sent a 304 and there is
+// nothing more to do. lastModified may be zero if the response has no single date.
+//
+// Anonymous requests get the given Cache-Control policy, so that a CDN can serve
+// them. Anyone signed in may be shown things others aren't, such as their own draft
+// products, so their responses are private and always revalidated.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time, policy string) bool {
+	w.Header().Add("Vary", "Authorization")
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	switch {
+	case !app.contextGetUser(r).IsAnonymous():
+		w.Header().Set("Cache-Control", "private, no-cache")
+	case policy != "":
+		w.Header().Set("Cache-Control", policy)
+	}
+
+	if !fresh(r, etag, lastModified) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// fresh reports whether the request's conditional headers match. If-None-Match takes
+// precedence over If-Modified-Since, and is compared weakly, as for any GET.
+func fresh(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates only have whole seconds.
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// listCategoriesHandler returns the whole taxonomy as a tree.
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := app.models.Categories.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": data.BuildCategoryTree(categories)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ParentID  *int64 `json:"parent_id"`
+		Name      string `json:"name"`
+		Slug      string `json:"slug"`
+		SortOrder int32  `json:"sort_order"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &data.Category{
+		ParentID:  input.ParentID,
+		Name:      strings.TrimSpace(input.Name),
+		Slug:      input.Slug,
+		SortOrder: input.SortOrder,
+	}
+	if category.Slug == "" {
+		category.Slug = data.Slugify(category.Name)
+	}
+
+	v := validator.New()
+
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Insert(category)
+	if err != nil {
+		app.categoryWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/categories/%d", category.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.models.Categories.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// parent_id is kept raw so that an explicit null (move to the top level) can be
+	// told apart from leaving it out.
+	var input struct {
+		ParentID  json.RawMessage `json:"parent_id"`
+		Name      *string         `json:"name"`
+		Slug      *string         `json:"slug"`
+		SortOrder *int32          `json:"sort_order"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	oldSlug := category.Slug
+
+	if input.ParentID != nil {
+		var parentID *int64
+		if err := json.Unmarshal(input.ParentID, &parentID); err != nil {
+			app.badRequestResponse(w, r, errors.New("body contains incorrect JSON type for \"parent_id\""))
+			return
+		}
+		category.ParentID = parentID
+	}
+	if input.Name != nil {
+		category.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Slug != nil {
+		category.Slug = *input.Slug
+	}
+	if input.SortOrder != nil {
+		category.SortOrder = *input.SortOrder
+	}
+
+	v := validator.New()
+
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Categories.Update(category, oldSlug)
+	if err != nil {
+		app.categoryWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Categories.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrCategoryInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the category still has subcategories or products")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// categoryWriteErrorResponse turns the errors returned when saving a category into
+// the matching response.
+func (app *application) categoryWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrEditConflict):
+		app.editConflictResponse(w, r)
+	case errors.Is(err, data.ErrDuplicateSlug):
+		v.AddError("slug", "a category with this slug already exists")
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrUnknownParent):
+		v.AddError("parent_id", "category does not exist")
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrCategoryCycle):
+		v.AddError("parent_id", "must not be one of the category's own subcategories")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// resolveProductCategories replaces the product's categories with their slugs from
+// the taxonomy, adding a validation error for any that don't exist.
+func (app *application) resolveProductCategories(product *data.Product, v *validator.Validator) error {
+	slugs, unknown, err := app.models.Categories.ResolveSlugs(product.Category)
+	if err != nil {
+		return err
+	}
+
+	if len(unknown) > 0 {
+		v.AddError("category", "contains unknown categories: "+strings.Join(unknown, ", "))
+		return nil
+	}
+
+	product.Category = slugs
+	return nil
+}
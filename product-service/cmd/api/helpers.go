@@ -9,14 +9,15 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
 	"github.com/go-chi/chi/v5"
 )
 
-func (app *application) readIDParam(r *http.Request) (int64, error) {
+func (app *application) readIDParam(r *http.Request, name string) (int64, error) {
 
-	params := chi.URLParam(r, "id")
+	params := chi.URLParam(r, name)
 
 	id, err := strconv.ParseInt(params, 10, 64)
 	if err != nil || id < 1 {
@@ -126,6 +127,24 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 
 }
 
+// readAttributeFilters collects the attr.<key>=<values> parameters of the query
+// string, such as attr.material=cotton,linen, into a map of key to comma-separated
+// values. It returns nil if there are none.
+func (app *application) readAttributeFilters(qs url.Values) map[string][]string {
+	var filters map[string][]string
+	for param := range qs {
+		key, ok := strings.CutPrefix(param, "attr.")
+		if !ok {
+			continue
+		}
+		if filters == nil {
+			filters = make(map[string][]string)
+		}
+		filters[key] = app.readCSV(qs, param, []string{})
+	}
+	return filters
+}
+
 // The readInt() helper reads a string value from the query string and converts it to an
 // integer before returning. If no matching key could be found it returns the provided
 // default value. If the value couldn't be converted to an integer, then we record an
@@ -151,6 +170,97 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 
 }
 
+// The readFloat() helper reads an optional decimal value from the query string. It
+// returns nil if the key is missing, and records an error in the provided Validator
+// instance if the value couldn't be converted.
+func (app *application) readFloat(qs url.Values, key string, v *validator.Validator) *float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return nil
+	}
+	return &f
+}
+
+// The readFloatCSV() helper reads a comma-separated list of decimal values from the
+// query string, returning the provided default value if the key is missing.
+func (app *application) readFloatCSV(qs url.Values, key string, defaultValue []float64, v *validator.Validator) []float64 {
+	csv := qs.Get(key)
+	if csv == "" {
+		return defaultValue
+	}
+
+	values := []float64{}
+	for _, s := range strings.Split(csv, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			v.AddError(key, "must be a comma-separated list of numbers")
+			return defaultValue
+		}
+		values = append(values, f)
+	}
+	return values
+}
+
+// The readBool() helper reads a boolean value from the query string, returning the
+// provided default value if the key is missing.
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+// runEvery calls fn every interval, and straight away as well if immediately is set,
+// until the server shuts down. The loop is tracked by app.wg, so shutdown waits for a
+// run that is under way to finish rather than cutting it off.
+func (app *application) runEvery(interval time.Duration, immediately bool, fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		if immediately {
+			fn()
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// shuttingDown reports whether the server has started shutting down. Long-running
+// background work checks it between steps so that it stops promptly.
+func (app *application) shuttingDown() bool {
+	select {
+	case <-app.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
 // // the background helper accepts an arbitrary function as a parameter
 func (app *application) background(fn func()) {
 	// Increment the WaitGroup counter.
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/imaging"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// Longest side, in pixels, of the renditions generated for each upload.
+const (
+	mediumSize    = 800
+	thumbnailSize = 200
+)
+
+// rendition is an encoded image ready to be stored.
+type rendition struct {
+	key         string
+	contentType string
+	body        []byte
+}
+
+func (app *application) uploadProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	maxBytes := app.config.images.maxBytes
+
+	// Leave some room for the multipart framing and the other form fields.
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+
+	err := r.ParseMultipartForm(8 << 20)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.contentTooLargeResponse(w, r, maxBytes)
+		default:
+			app.badRequestResponse(w, r, errors.New("body must be a multipart form"))
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	v := validator.New()
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		v.AddError("image", "must be provided")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxBytes {
+		app.contentTooLargeResponse(w, r, maxBytes)
+		return
+	}
+
+	original, err := io.ReadAll(file)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Trust the bytes rather than the Content-Type the client sent.
+	contentType, err := imaging.Sniff(original[:min(len(original), 512)])
+	if err != nil {
+		v.AddError("image", "must be a JPEG, PNG or GIF image")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	img, err := imaging.Decode(original)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrTooManyPixels):
+			v.AddError("image", "dimensions are too large")
+		default:
+			v.AddError("image", "could not be read as an image")
+		}
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	primary := false
+	if s := r.FormValue("primary"); s != "" {
+		primary, err = strconv.ParseBool(s)
+		if err != nil {
+			v.AddError("primary", "must be a boolean value")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	prefix := fmt.Sprintf("products/%d/%s", product.ID, token)
+
+	renditions := []rendition{{
+		key:         prefix + "." + imaging.SupportedTypes[contentType],
+		contentType: contentType,
+		body:        original,
+	}}
+
+	for _, size := range []struct {
+		name    string
+		maxSide int
+	}{{"medium", mediumSize}, {"thumb", thumbnailSize}} {
+		var buf bytes.Buffer
+		encodedType, err := imaging.Encode(&buf, imaging.Fit(img, size.maxSide), contentType)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		renditions = append(renditions, rendition{
+			key:         fmt.Sprintf("%s-%s.%s", prefix, size.name, imaging.SupportedTypes[encodedType]),
+			contentType: encodedType,
+			body:        buf.Bytes(),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	keys := []string{}
+	for _, rd := range renditions {
+		err = app.storage.Put(ctx, rd.key, bytes.NewReader(rd.body), rd.contentType)
+		if err != nil {
+			app.deleteStoredFiles(keys)
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		keys = append(keys, rd.key)
+	}
+
+	image := &data.ProductImage{
+		ProductID:    product.ID,
+		IsPrimary:    primary,
+		URL:          app.storage.URL(renditions[0].key),
+		MediumURL:    app.storage.URL(renditions[1].key),
+		ThumbnailURL: app.storage.URL(renditions[2].key),
+		StorageKeys:  keys,
+		ContentType:  contentType,
+		Width:        img.Bounds().Dx(),
+		Height:       img.Bounds().Dy(),
+		SizeBytes:    int64(len(original)),
+	}
+
+	err = app.models.Images.Insert(image)
+	if err != nil {
+		app.deleteStoredFiles(keys)
+		switch {
+		case errors.Is(err, data.ErrTooManyImages):
+			v.AddError("image", fmt.Sprintf("a product can't have more than %d images", data.MaxImagesPerProduct))
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/products/%d/images/%d", product.ID, image.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"image": image}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listProductImagesHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	images, err := app.models.Images.GetAllForProduct(product.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"images": images}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	imageID, err := app.readIDParam(r, "image_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	image, err := app.models.Images.Get(imageID, product.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Position *int32 `json:"position"`
+		Primary  *bool  `json:"primary"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Position != nil {
+		image.Position = *input.Position
+	}
+	if input.Primary != nil {
+		image.IsPrimary = *input.Primary
+	}
+
+	v := validator.New()
+	v.Check(image.Position >= 0, "position", "must be zero or greater")
+	v.Check(image.Position <= 1000, "position", "must not be more than 1000")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Images.Update(image)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"image": image}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	imageID, err := app.readIDParam(r, "image_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	image, err := app.models.Images.Delete(imageID, product.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.deleteStoredFiles(image.StorageKeys)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "image deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteStoredFiles removes files from storage in the background. Failures are only
+// logged; an orphaned file is harmless.
+func (app *application) deleteStoredFiles(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	app.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		for _, key := range keys {
+			if err := app.storage.Delete(ctx, key); err != nil {
+				app.logger.PrintError(err, map[string]string{"storage_key": key})
+			}
+		}
+	})
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// importProductsHandler queues a CSV or NDJSON file of products to be imported. The
+// file is checked for structure straight away; its rows are validated and saved by a
+// background job, whose progress and per-row errors are at the Location returned.
+func (app *application) importProductsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = "csv"
+		case "application/x-ndjson", "application/ndjson":
+			format = "ndjson"
+		default:
+			app.errorResponse(w, r, http.StatusUnsupportedMediaType, "send text/csv or application/x-ndjson, or give the format parameter")
+			return
+		}
+	}
+	if v.Check(validator.In(format, data.ImportFormats...), "format", "must be csv or ndjson"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	maxBytes := app.config.imports.maxBytes
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.contentTooLargeResponse(w, r, maxBytes)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	rows, err := parseCatalogue(format, payload)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v.Check(len(rows) > 0, "file", "must contain at least one product")
+	v.Check(len(rows) <= maxImportRows, "file", fmt.Sprintf("must not contain more than %d products", maxImportRows))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	job := &data.ImportJob{
+		UserID:    app.contextGetUser(r).ID,
+		Format:    format,
+		TotalRows: len(rows),
+	}
+
+	err = app.models.Imports.Insert(job, payload)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		app.runImport(job.ID)
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/imports/%d", job.ID))
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"import": job}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.models.Imports.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import": job}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// exportProductsHandler streams all of the seller's products, in the format the import
+// accepts, so that a catalogue can be edited offline and imported again.
+func (app *application) exportProductsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	format := app.readString(r.URL.Query(), "format", "csv")
+	if v.Check(validator.In(format, data.ImportFormats...), "format", "must be csv or ndjson"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// A large catalogue can take longer than the server's usual write timeout.
+	const exportTimeout = 2 * time.Minute
+	ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+	defer cancel()
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportTimeout))
+
+	// Nothing is sent until the first product is written, so that a failed query can
+	// still get an error response.
+	started := false
+	start := func() {
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+		started = true
+	}
+
+	var write func(*data.Product) error
+	csvWriter := csv.NewWriter(w)
+	if format == "csv" {
+		write = func(product *data.Product) error {
+			if !started {
+				start()
+				if err := csvWriter.Write(catalogueColumns); err != nil {
+					return err
+				}
+			}
+			return csvWriter.Write(catalogueRowFor(product).record())
+		}
+	} else {
+		enc := json.NewEncoder(w)
+		write = func(product *data.Product) error {
+			if !started {
+				start()
+			}
+			return enc.Encode(catalogueRowFor(product))
+		}
+	}
+
+	err := app.models.Products.ForEachOwned(ctx, app.contextGetUser(r).ID, write)
+	if err == nil && !started {
+		// An empty catalogue still gets a CSV header.
+		start()
+		if format == "csv" {
+			err = csvWriter.Write(catalogueColumns)
+		}
+	}
+	csvWriter.Flush()
+	if err == nil {
+		err = csvWriter.Error()
+	}
+
+	if err != nil {
+		if !started {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		// The response is already under way, so all that can be done is to log it.
+		app.logger.PrintError(err, map[string]string{"component": "export"})
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+const (
+	maxImportRows = 10000
+	// A running import saves its progress every importProgressEvery rows.
+	importProgressEvery = 100
+)
+
+// catalogueColumns are the columns of an exported CSV file, in order. Imports need the
+// required ones and may leave the rest out.
+var catalogueColumns = []string{"sku", "name", "description", "price", "image_url", "stock", "category", "status", "low_stock_threshold"}
+
+var requiredCatalogueColumns = []string{"sku", "name", "description", "price", "stock", "category"}
+
+// catalogueRow is a product as it appears in an import or export file. In CSV files
+// categories are separated by "|".
+type catalogueRow struct {
+	SKU               string   `json:"sku"`
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	Price             rowPrice `json:"price"`
+	ImageUrl          string   `json:"image_url"`
+	Stock             int32    `json:"stock"`
+	Category          []string `json:"category"`
+	Status            string   `json:"status,omitempty"`
+	LowStockThreshold *int32   `json:"low_stock_threshold,omitempty"`
+}
+
+// rowPrice is a plain decimal price. Unlike data.Price it is written as a JSON number,
+// and is read from either a number or a string.
+type rowPrice float64
+
+func (p rowPrice) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(float64(p), 'f', 2, 64)), nil
+}
+
+func (p *rowPrice) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	price, err := data.ParsePrice(s)
+	if err != nil {
+		return err
+	}
+	*p = rowPrice(price)
+	return nil
+}
+
+// parsedRow is a row read from an import file. Errors holds any fields that couldn't
+// be read; the row is reported rather than imported if there are any.
+type parsedRow struct {
+	catalogueRow
+	Line   int
+	Errors map[string]string
+}
+
+func (row catalogueRow) record() []string {
+	threshold := ""
+	if row.LowStockThreshold != nil {
+		threshold = strconv.Itoa(int(*row.LowStockThreshold))
+	}
+	return []string{
+		row.SKU,
+		row.Name,
+		row.Description,
+		strconv.FormatFloat(float64(row.Price), 'f', 2, 64),
+		row.ImageUrl,
+		strconv.Itoa(int(row.Stock)),
+		strings.Join(row.Category, "|"),
+		row.Status,
+		threshold,
+	}
+}
+
+func catalogueRowFor(product *data.Product) catalogueRow {
+	return catalogueRow{
+		SKU:               product.SKU,
+		Name:              product.Name,
+		Description:       product.Description,
+		Price:             rowPrice(product.Price),
+		ImageUrl:          product.ImageUrl,
+		Stock:             product.Stock,
+		Category:          product.Category,
+		Status:            product.Status,
+		LowStockThreshold: product.LowStockThreshold,
+	}
+}
+
+// parseCatalogue reads the rows of an import file. It only returns an error if the
+// file as a whole can't be read; problems with individual rows are left in their
+// Errors.
+func parseCatalogue(format string, payload []byte) ([]parsedRow, error) {
+	switch format {
+	case "csv":
+		return parseCatalogueCSV(payload)
+	case "ndjson":
+		return parseCatalogueNDJSON(payload)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func parseCatalogueCSV(payload []byte) ([]parsedRow, error) {
+	reader := csv.NewReader(bytes.NewReader(payload))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !validator.In(name, catalogueColumns...) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("column %q appears more than once", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredCatalogueColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	rows := []parsedRow{}
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", line, err)
+		}
+
+		row := parsedRow{Line: line, Errors: map[string]string{}}
+		if len(record) != len(header) {
+			row.Errors["row"] = fmt.Sprintf("has %d fields, the header has %d", len(record), len(header))
+			rows = append(rows, row)
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row.SKU = field("sku")
+		row.Name = field("name")
+		row.Description = field("description")
+		row.ImageUrl = field("image_url")
+		row.Status = field("status")
+
+		if price, err := data.ParsePrice(field("price")); err != nil {
+			row.Errors["price"] = "must be a number"
+		} else {
+			row.Price = rowPrice(price)
+		}
+
+		if s := field("stock"); s != "" {
+			stock, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				row.Errors["stock"] = "must be an integer"
+			}
+			row.Stock = int32(stock)
+		}
+
+		for _, category := range strings.Split(field("category"), "|") {
+			if category = strings.TrimSpace(category); category != "" {
+				row.Category = append(row.Category, category)
+			}
+		}
+
+		if s := field("low_stock_threshold"); s != "" {
+			threshold, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				row.Errors["low_stock_threshold"] = "must be an integer"
+			}
+			t := int32(threshold)
+			row.LowStockThreshold = &t
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseCatalogueNDJSON(payload []byte) ([]parsedRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	rows := []parsedRow{}
+
+	for line := 0; scanner.Scan(); {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		line++
+
+		row := parsedRow{Line: line, Errors: map[string]string{}}
+
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&row.catalogueRow); err != nil {
+			row.Errors["row"] = "is not a valid product: " + strings.TrimPrefix(err.Error(), "json: ")
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// resumeImports runs any imports that were queued or running when the service last
+// stopped.
+func (app *application) resumeImports() {
+	ids, err := app.models.Imports.GetUnfinished()
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"component": "import"})
+		return
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	app.background(func() {
+		for _, id := range ids {
+			app.runImport(id)
+		}
+	})
+}
+
+// runImport processes an import job, creating or updating a product for each valid
+// row.
+func (app *application) runImport(id int64) {
+	job, payload, err := app.models.Imports.Start(id)
+	if err != nil {
+		if !errors.Is(err, data.ErrRecordNotFound) {
+			app.logger.PrintError(err, map[string]string{"component": "import", "import_id": strconv.FormatInt(id, 10)})
+		}
+		return
+	}
+
+	finish := func() {
+		if err := app.models.Imports.Finish(job); err != nil {
+			app.logger.PrintError(err, map[string]string{"component": "import", "import_id": strconv.FormatInt(id, 10)})
+		}
+	}
+
+	rows, err := parseCatalogue(job.Format, payload)
+	if err != nil {
+		job.Status = data.ImportFailed
+		job.Error = err.Error()
+		finish()
+		return
+	}
+
+	// The job runs without the request that queued it, so the seller is treated as
+	// having no permissions: with -require-product-review even moderators' imports
+	// are held for review.
+	seller := &data.User{ID: job.UserID}
+
+	for i, row := range rows {
+		created, rowErrors, err := app.importProduct(seller, row)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"component": "import", "import_id": strconv.FormatInt(id, 10)})
+			job.Status = data.ImportFailed
+			job.Error = fmt.Sprintf("stopped at row %d by an internal error", row.Line)
+			finish()
+			return
+		}
+
+		switch {
+		case rowErrors != nil:
+			job.FailedRows++
+			job.RowErrors = append(job.RowErrors, data.ImportRowError{Row: row.Line, SKU: row.SKU, Errors: rowErrors})
+		case created:
+			job.CreatedRows++
+		default:
+			job.UpdatedRows++
+		}
+		job.ProcessedRows++
+
+		if (i+1)%importProgressEvery == 0 {
+			if err := app.models.Imports.SaveProgress(job); err != nil {
+				app.logger.PrintError(err, map[string]string{"component": "import", "import_id": strconv.FormatInt(id, 10)})
+			}
+		}
+	}
+
+	job.Status = data.ImportCompleted
+	finish()
+}
+
+// importProduct creates or updates the seller's product with the row's SKU. It returns
+// the validation errors if the row can't be imported, and an error only if something
+// went wrong that isn't the row's fault.
+func (app *application) importProduct(seller *data.User, row parsedRow) (bool, map[string]string, error) {
+	if len(row.Errors) > 0 {
+		return false, row.Errors, nil
+	}
+
+	v := validator.New()
+
+	if v.Check(row.SKU != "", "sku", "must be provided"); !v.Valid() {
+		return false, v.Errors, nil
+	}
+
+	created := false
+	product, err := app.models.Products.GetBySKU(seller.ID, row.SKU)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			product = &data.Product{UserId: seller.ID, SKU: row.SKU, Status: data.ProductDraft}
+			created = true
+		default:
+			return false, nil, err
+		}
+	}
+
+	product.Name = row.Name
+	product.Description = row.Description
+	product.Price = data.Price(row.Price)
+	product.ImageUrl = row.ImageUrl
+	product.Stock = row.Stock
+	product.Category = row.Category
+	product.LowStockThreshold = row.LowStockThreshold
+
+	if row.Status != "" && row.Status != product.Status {
+		if created {
+			v.Check(row.Status != data.ProductArchived, "status", "must not be archived for a new product")
+		} else {
+			data.ValidateStatusChange(v, product.Status, row.Status)
+		}
+		product.Status = row.Status
+		app.holdForReview(product, seller)
+	}
+
+	if err := app.resolveProductCategories(product, v); err != nil {
+		return false, nil, err
+	}
+
+	// Imports don't carry attributes, so an updated product keeps its own, but they
+	// are still checked in case the row moved it to other categories.
+	definitions, err := app.productAttributeDefinitions(product)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if data.ValidateProduct(v, product, definitions); !v.Valid() {
+		return false, v.Errors, nil
+	}
+
+	if created {
+		err = app.models.Products.Insert(product)
+	} else {
+		err = app.models.Products.Update(product, seller.ID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "was used by another product while importing")
+		case errors.Is(err, data.ErrEditConflict):
+			v.AddError("sku", "the product was changed while importing, please try again")
+		default:
+			return false, nil, err
+		}
+		return false, v.Errors, nil
+	}
+
+	return created, nil, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/lib/pq"
+)
+
+// startCacheInvalidation listens for product changes from every instance, and from
+// triggers, and drops them from the catalogue cache. If it can't listen, the cache is
+// turned off, since otherwise it would serve other instances' stale products until
+// they expired. The listener is closed when the server shuts down.
+func (app *application) startCacheInvalidation() {
+	cache := app.models.Products.Cache
+	if cache == nil {
+		return
+	}
+
+	listener := pq.NewListener(app.config.db.dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"component": "cache"})
+		}
+		// Changes made while the connection is down are never notified.
+		if event == pq.ListenerEventDisconnected {
+			cache.InvalidateAll()
+		}
+	})
+
+	err := listener.Listen(data.ProductChangesChannel)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"component": "cache"})
+		app.logger.PrintInfo("catalogue cache disabled: cannot listen for product changes", nil)
+		listener.Close()
+		app.models.Products.Cache = nil
+		return
+	}
+
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+		defer listener.Close()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		for {
+			select {
+			case <-app.shutdown:
+				return
+			case n := <-listener.Notify:
+				// A nil notification means the connection was re-established.
+				if n == nil {
+					cache.InvalidateAll()
+					continue
+				}
+				if n.Extra == "" {
+					cache.InvalidateLists()
+					continue
+				}
+				id, err := strconv.ParseInt(n.Extra, 10, 64)
+				if err != nil {
+					cache.InvalidateAll()
+					continue
+				}
+				cache.Invalidate(id)
+			case <-time.After(90 * time.Second):
+				// Check the connection is still there when it has been quiet for a while.
+				go listener.Ping()
+			}
+		}
+	}()
+}
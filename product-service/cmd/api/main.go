@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"expvar"
 	"flag"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +18,8 @@ import (
 	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
 	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/jsonlog"
 	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/jwt"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/notify"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/storage"
 	_ "github.com/lib/pq"
 )
 
@@ -30,9 +35,14 @@ type config struct {
 		maxIdleTime  string
 	}
 	limiter struct {
-		rps     float64
-		burst   int
-		enabled bool
+		rps          float64
+		burst        int
+		enabled      bool
+		suggestRPS   float64
+		suggestBurst int
+	}
+	suggest struct {
+		timeout time.Duration
 	}
 	jwt struct {
 		publicKeyPath    string
@@ -42,12 +52,50 @@ type config struct {
 	userService struct {
 		url string
 	}
+	orderService struct {
+		url string
+	}
 	cache struct {
-		userTTL time.Duration
+		userTTL        time.Duration
+		productEntries int
+		listEntries    int
+		catalogueTTL   time.Duration
+	}
+	httpCache struct {
+		product string
+		list    string
 	}
 	cors struct {
 		trustedOrigins []string
 	}
+	storage struct {
+		dir     string
+		baseURL string
+	}
+	images struct {
+		maxBytes int64
+	}
+	facets struct {
+		priceBuckets []float64
+	}
+	products struct {
+		requireReview    bool
+		scheduleInterval time.Duration
+	}
+	imports struct {
+		maxBytes int64
+	}
+	recommendations struct {
+		serviceToken string
+		interval     time.Duration
+	}
+	notify struct {
+		channel       string
+		webhookURL    string
+		webhookSecret string
+		serviceToken  string
+		interval      time.Duration
+	}
 }
 
 type application struct {
@@ -55,9 +103,12 @@ type application struct {
 	logger       *jsonlog.Logger
 	models       data.Models
 	wg           sync.WaitGroup
+	shutdown     chan struct{}
 	jwtValidator *jwt.JWTValidator
 	httpClient   *http.Client
 	userCache    *cache.UserCache
+	storage      storage.Storage
+	notifier     notify.Notifier
 }
 
 func main() {
@@ -77,6 +128,11 @@ func main() {
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 2, "Rate limiter requests per second")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
+	flag.Float64Var(&cfg.limiter.suggestRPS, "limiter-suggest-rps", 10, "Autocomplete rate limiter requests per second")
+	flag.IntVar(&cfg.limiter.suggestBurst, "limiter-suggest-burst", 20, "Autocomplete rate limiter burst")
+
+	// Autocomplete config
+	flag.DurationVar(&cfg.suggest.timeout, "suggest-timeout", 300*time.Millisecond, "Time limit for an autocomplete query")
 
 	// JWT config
 	flag.StringVar(&cfg.jwt.publicKeyPath, "jwt-public-key", os.Getenv("JWT_PUBLIC_KEY"), "Path to JWT public key")
@@ -86,8 +142,43 @@ func main() {
 	// User service config
 	flag.StringVar(&cfg.userService.url, "user-service-url", os.Getenv("USER_SERVICE_URL"), "User service URL")
 
+	// Order service config
+	flag.StringVar(&cfg.orderService.url, "order-service-url", os.Getenv("ORDER_SERVICE_URL"), "Order service URL")
+
 	// Cache config
 	flag.DurationVar(&cfg.cache.userTTL, "cache-user-ttl", 5*time.Minute, "User cache TTL")
+	flag.IntVar(&cfg.cache.productEntries, "cache-product-entries", 10000, "Most products held in the in-memory catalogue cache (0 turns it off)")
+	flag.IntVar(&cfg.cache.listEntries, "cache-list-entries", 1000, "Most product list pages held in the in-memory catalogue cache (0 turns it off)")
+	flag.DurationVar(&cfg.cache.catalogueTTL, "cache-catalogue-ttl", time.Minute, "Longest time a product or product list page is held in the catalogue cache")
+
+	// HTTP caching config. These Cache-Control policies apply to anonymous requests for
+	// products and the product list; max-age=0 has browsers revalidate with the ETag
+	// every time, while s-maxage lets a CDN serve them for a while.
+	flag.StringVar(&cfg.httpCache.product, "cache-control-product", "public, max-age=0, s-maxage=60, stale-while-revalidate=300", "Cache-Control header of public product responses")
+	flag.StringVar(&cfg.httpCache.list, "cache-control-list", "public, max-age=0, s-maxage=30, stale-while-revalidate=60", "Cache-Control header of public product list responses")
+
+	// Image upload config
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory uploaded images are stored in")
+	flag.StringVar(&cfg.storage.baseURL, "storage-base-url", "http://localhost:5000/uploads", "Public URL uploaded images are served from")
+	flag.Int64Var(&cfg.images.maxBytes, "image-max-bytes", 10<<20, "Maximum size of an uploaded image in bytes")
+
+	// Product lifecycle config
+	flag.BoolVar(&cfg.products.requireReview, "require-product-review", false, "Hold products for approval by a moderator before they are published")
+	flag.DurationVar(&cfg.products.scheduleInterval, "schedule-interval", time.Minute, "How often scheduled publishing and unpublishing is applied")
+
+	// Bulk import config
+	flag.Int64Var(&cfg.imports.maxBytes, "import-max-bytes", 10<<20, "Maximum size of a product import file in bytes")
+
+	// Recommendations config
+	flag.StringVar(&cfg.recommendations.serviceToken, "recommendations-service-token", os.Getenv("RECOMMENDATIONS_SERVICE_TOKEN"), "user-service token with the orders:co-purchases permission, for bought-together recommendations")
+	flag.DurationVar(&cfg.recommendations.interval, "recommendations-interval", time.Hour, "How often bought-together recommendations are brought up to date")
+
+	// Notification config
+	flag.StringVar(&cfg.notify.channel, "notifier", "log", "How notifications are sent (log|webhook|email)")
+	flag.StringVar(&cfg.notify.webhookURL, "notifier-webhook-url", "", "URL notifications are POSTed to by the webhook notifier")
+	flag.StringVar(&cfg.notify.webhookSecret, "notifier-webhook-secret", os.Getenv("NOTIFIER_WEBHOOK_SECRET"), "Key the webhook notifier signs requests with")
+	flag.StringVar(&cfg.notify.serviceToken, "notifier-service-token", os.Getenv("NOTIFIER_SERVICE_TOKEN"), "user-service token with the notifications:send permission, for the email notifier")
+	flag.DurationVar(&cfg.notify.interval, "notifier-interval", 10*time.Second, "How often queued notifications are sent")
 
 	// Use the flag.Func() function to process the -cors-trusted-origins command line
 	// flag.
@@ -96,6 +187,25 @@ func main() {
 		return nil
 	})
 
+	// Default lower bounds of the price facet's buckets; clients can override them with
+	// the price_buckets query parameter.
+	cfg.facets.priceBuckets = []float64{0, 25, 50, 100, 250}
+	flag.Func("facet-price-buckets", "Default price facet bucket bounds (comma separated, ascending)", func(val string) error {
+		buckets := []float64{}
+		for _, s := range strings.Split(val, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return err
+			}
+			buckets = append(buckets, f)
+		}
+		if !sort.Float64sAreSorted(buckets) {
+			return errors.New("bucket bounds must be in ascending order")
+		}
+		cfg.facets.priceBuckets = buckets
+		return nil
+	})
+
 	flag.Parse()
 
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
@@ -103,6 +213,9 @@ func main() {
 	if cfg.userService.url == "" {
 		logger.PrintFatal(errors.New("USER_SERVICE_URL is required"), nil)
 	}
+	if cfg.orderService.url == "" {
+		logger.PrintFatal(errors.New("ORDER_SERVICE_URL is required"), nil)
+	}
 
 	// Database connection
 	db, err := openDB(cfg)
@@ -136,15 +249,63 @@ func main() {
 		"ttl": cfg.cache.userTTL.String(),
 	})
 
+	// Products and public product lists are cached in memory, and dropped when they
+	// change. Its counters are published on /debug/vars.
+	var catalogueCache *data.CatalogueCache
+	if cfg.cache.productEntries > 0 || cfg.cache.listEntries > 0 {
+		catalogueCache = data.NewCatalogueCache(cfg.cache.productEntries, cfg.cache.listEntries, cfg.cache.catalogueTTL)
+		expvar.Publish("catalogue_cache", expvar.Func(func() any {
+			return catalogueCache.Stats()
+		}))
+	}
+
+	// Uploaded images are kept on the local filesystem and served from /uploads.
+	imageStorage, err := storage.NewLocal(cfg.storage.dir, cfg.storage.baseURL)
+	if err != nil {
+		logger.PrintFatal(err, map[string]string{
+			"component": "storage",
+		})
+	}
+
+	var notifier notify.Notifier
+	switch cfg.notify.channel {
+	case "log":
+		notifier = notify.Log{Logger: logger}
+	case "webhook":
+		if cfg.notify.webhookURL == "" {
+			logger.PrintFatal(errors.New("-notifier-webhook-url is required by the webhook notifier"), nil)
+		}
+		notifier = notify.Webhook{URL: cfg.notify.webhookURL, Secret: cfg.notify.webhookSecret, Client: httpClient}
+	case "email":
+		if cfg.notify.serviceToken == "" {
+			logger.PrintFatal(errors.New("NOTIFIER_SERVICE_TOKEN is required by the email notifier"), nil)
+		}
+		notifier = notify.Email{UserServiceURL: cfg.userService.url, Token: cfg.notify.serviceToken, Client: httpClient}
+	default:
+		logger.PrintFatal(errors.New("-notifier must be log, webhook or email"), nil)
+	}
+
+	models := data.NewModels(db)
+	models.Products.Cache = catalogueCache
+
 	app := &application{
 		config:       cfg,
 		logger:       logger,
-		models:       data.NewModels(db),
+		models:       models,
+		shutdown:     make(chan struct{}),
 		jwtValidator: jwtValidator,
 		httpClient:   httpClient,
 		userCache:    userCache,
+		storage:      imageStorage,
+		notifier:     notifier,
 	}
 
+	app.startCacheInvalidation()
+	app.startNotificationDispatcher()
+	app.startProductScheduler()
+	app.resumeImports()
+	app.startCoPurchaseSync()
+
 	err = app.serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -40,18 +40,27 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *application) rateLimit(next http.Handler) http.Handler {
-	// Define a client struct to hold the rate limiter and last seen time for each
-	// client.
-	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
+// ipRateLimiter holds a token-bucket rate limiter per client IP address.
+type ipRateLimiter struct {
+	mu      sync.Mutex
+	clients map[string]*rateLimitClient
+	rps     float64
+	burst   int
+}
+
+// rateLimitClient holds the rate limiter and last seen time for each client.
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newIPRateLimiter(rps float64, burst int) *ipRateLimiter {
+	l := &ipRateLimiter{
+		clients: make(map[string]*rateLimitClient),
+		rps:     rps,
+		burst:   burst,
 	}
-	var (
-		mu sync.Mutex
-		// Update the map so the values are pointers to a client struct.
-		clients = make(map[string]*client)
-	)
+
 	// Launch a background goroutine which removes old entries from the clients map once
 	// every minute.
 	go func() {
@@ -59,18 +68,37 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			time.Sleep(time.Minute)
 			// Lock the mutex to prevent any rate limiter checks from happening while
 			// the cleanup is taking place.
-			mu.Lock()
+			l.mu.Lock()
 			// Loop through all clients. If they haven't been seen within the last three
 			// minutes, delete the corresponding entry from the map.
-			for ip, client := range clients {
+			for ip, client := range l.clients {
 				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+					delete(l.clients, ip)
 				}
 			}
-			// Importantly, unlock the mutex when the cleanup is complete.
-			mu.Unlock()
+			l.mu.Unlock()
 		}
 	}()
+
+	return l
+}
+
+// allow reports whether a request from ip may go ahead.
+func (l *ipRateLimiter) allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, found := l.clients[ip]; !found {
+		l.clients[ip] = &rateLimitClient{
+			limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst),
+		}
+	}
+	l.clients[ip].lastSeen = time.Now()
+	return l.clients[ip].limiter.Allow()
+}
+
+// limitByIP rejects requests from clients that have gone over the limiter's rate.
+func (app *application) limitByIP(limiter *ipRateLimiter, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Only carry out the check if rate limiting is enabled.
 		if app.config.limiter.enabled {
@@ -79,26 +107,35 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 				app.serverErrorResponse(w, r, err)
 				return
 			}
-			mu.Lock()
-			if _, found := clients[ip]; !found {
-				clients[ip] = &client{
-					// Use the requests-per-second and burst values from the config
-					// struct.
-					limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
-				}
-			}
-			clients[ip].lastSeen = time.Now()
-			if !clients[ip].limiter.Allow() {
-				mu.Unlock()
+			if !limiter.allow(ip) {
 				app.rateLimitExceededResponse(w, r)
 				return
 			}
-			mu.Unlock()
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// rateLimit applies the main API rate limit. Autocomplete requests, which arrive on
+// every keystroke, are left to their own limit in suggestRateLimit.
+func (app *application) rateLimit(next http.Handler) http.Handler {
+	limited := app.limitByIP(newIPRateLimiter(app.config.limiter.rps, app.config.limiter.burst), next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == suggestPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limited.ServeHTTP(w, r)
+	})
+}
+
+// suggestRateLimit applies the autocomplete rate limit.
+func (app *application) suggestRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	limiter := newIPRateLimiter(app.config.limiter.suggestRPS, app.config.limiter.suggestBurst)
+	return app.limitByIP(limiter, next).ServeHTTP
+}
+
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
@@ -189,6 +226,22 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 	return app.requireAuthenticatedUser(fn)
 }
 
+// requirePermission checks that the user holds a specific permission code. Unlike
+// user-service we don't own the permissions tables, so the codes come from the user
+// details fetched (and cached) during authenticate().
+func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !user.Permissions.Include(code) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	// Wrap this with the requireActivatedUser() middleware before returning it.
+	return app.requireActivatedUser(fn)
+}
+
 // if your code makes a decision about what to return based on the content of a request header,
 // you should include that header name in your Vary response header — even if the request
 // didn’t include that header
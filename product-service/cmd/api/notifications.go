@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/notify"
+)
+
+const (
+	notificationBatchSize = 50
+	// notificationLease is how long a claimed notification is left to its dispatcher
+	// before another may try it.
+	notificationLease = 2 * time.Minute
+	// Notifications that still fail after this many attempts are given up on and left
+	// in the outbox with their last error.
+	notificationMaxAttempts = 8
+)
+
+// startNotificationDispatcher sends the notifications queued in the outbox every
+// notifier-interval, through the configured notifier, until the server shuts down.
+func (app *application) startNotificationDispatcher() {
+	app.runEvery(app.config.notify.interval, false, app.dispatchNotifications)
+}
+
+// dispatchNotifications sends everything that is due. A failed notification is tried
+// again later, backing off exponentially. On shutdown it finishes the batch it has
+// claimed, so that nothing is left claimed or sent twice, and stops.
+func (app *application) dispatchNotifications() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.PrintError(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	for {
+		batch, err := app.models.Notifications.ClaimDue(notificationBatchSize, notificationLease, notificationMaxAttempts)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"component": "notifier"})
+			return
+		}
+
+		for _, n := range batch {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			err := app.notifier.Notify(ctx, notify.Message{
+				ID:      n.ID,
+				UserID:  n.UserID,
+				Kind:    n.Kind,
+				Subject: n.Subject,
+				Body:    n.Message,
+				Payload: n.Payload,
+			})
+			cancel()
+
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"component":       "notifier",
+					"notification_id": fmt.Sprintf("%d", n.ID),
+					"attempt":         fmt.Sprintf("%d", n.Attempts),
+				})
+				retryAt := time.Now().Add(30 * time.Second << min(n.Attempts-1, 10))
+				if err := app.models.Notifications.MarkFailed(n.ID, err, retryAt); err != nil {
+					app.logger.PrintError(err, map[string]string{"component": "notifier"})
+				}
+				continue
+			}
+
+			if err := app.models.Notifications.MarkSent(n.ID); err != nil {
+				app.logger.PrintError(err, map[string]string{"component": "notifier"})
+			}
+		}
+
+		if len(batch) < notificationBatchSize || app.shuttingDown() {
+			return
+		}
+	}
+}
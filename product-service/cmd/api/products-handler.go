@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
@@ -13,12 +15,27 @@ import (
 func (app *application) createProductHandler(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
+		SKU         string     `json:"sku"`
 		Name        string     `json:"name"`
 		Description string     `json:"description"`
 		Price       data.Price `json:"price"`
 		ImageUrl    string     `json:"image_url"`
 		Stock       int32      `json:"stock"`
 		Category    []string   `json:"category"`
+		// LowStockThreshold is optional; leaving it out turns low-stock warnings off.
+		LowStockThreshold *int32 `json:"low_stock_threshold"`
+		// New products are drafts unless they say otherwise.
+		Status      string     `json:"status"`
+		PublishAt   *time.Time `json:"publish_at"`
+		UnpublishAt *time.Time `json:"unpublish_at"`
+		// A sale is optional, as is a compare-at price.
+		CompareAtPrice *data.Price `json:"compare_at_price"`
+		SalePrice      *data.Price `json:"sale_price"`
+		SaleStartsAt   *time.Time  `json:"sale_starts_at"`
+		SaleEndsAt     *time.Time  `json:"sale_ends_at"`
+		// Attributes are values for the attributes defined on the categories.
+		Attributes map[string]any `json:"attributes"`
+		BrandID    *int64         `json:"brand_id"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -37,12 +54,27 @@ func (app *application) createProductHandler(w http.ResponseWriter, r *http.Requ
 
 	product := &data.Product{
 		UserId:      user.ID,
+		SKU:         input.SKU,
 		Name:        input.Name,
 		Description: input.Description,
 		Price:       input.Price,
 		ImageUrl:    input.ImageUrl,
 		Stock:       input.Stock,
 		Category:    input.Category,
+
+		LowStockThreshold: input.LowStockThreshold,
+		Status:            input.Status,
+		PublishAt:         input.PublishAt,
+		UnpublishAt:       input.UnpublishAt,
+		CompareAtPrice:    input.CompareAtPrice,
+		SalePrice:         input.SalePrice,
+		SaleStartsAt:      input.SaleStartsAt,
+		SaleEndsAt:        input.SaleEndsAt,
+		Attributes:        input.Attributes,
+		BrandID:           input.BrandID,
+	}
+	if product.Status == "" {
+		product.Status = data.ProductDraft
 	}
 
 	// app.logger.PrintInfo("Product struct ", map[string]string{
@@ -52,14 +84,41 @@ func (app *application) createProductHandler(w http.ResponseWriter, r *http.Requ
 	// Initialize a new Validator instance.
 	v := validator.New()
 
-	if data.ValidateProduct(v, product); !v.Valid() {
+	v.Check(product.Status != data.ProductArchived, "status", "must not be archived for a new product")
+	if product.UnpublishAt != nil {
+		v.Check(product.UnpublishAt.After(time.Now()), "unpublish_at", "must be in the future")
+	}
+	app.holdForReview(product, user)
+
+	err = app.resolveProductCategories(product, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	definitions, err := app.productAttributeDefinitions(product)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateProduct(v, product, definitions); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
 	err = app.models.Products.Insert(product)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "is already used by another of your products")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownBrand):
+			v.AddError("brand_id", "brand does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 	headers := make(http.Header)
@@ -73,20 +132,26 @@ func (app *application) createProductHandler(w http.ResponseWriter, r *http.Requ
 }
 
 func (app *application) showProductHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	lastModified := product.LastModified(time.Now())
+	if app.notModified(w, r, productETag(product, lastModified), lastModified, app.config.httpCache.product) {
+		return
+	}
+
+	var err error
+	product.Variants, err = app.models.Variants.GetAllForProduct(product.ID)
 	if err != nil {
-		app.notFoundResponse(w, r)
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	product, err := app.models.Products.Get(id)
+	product.Images, err = app.models.Images.GetAllForProduct(product.ID)
 	if err != nil {
-		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			app.notFoundResponse(w, r)
-		default:
-			app.serverErrorResponse(w, r, err)
-		}
+		app.serverErrorResponse(w, r, err)
 		return
 	}
 
@@ -97,7 +162,7 @@ func (app *application) showProductHandler(w http.ResponseWriter, r *http.Reques
 }
 
 func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
+	id, err := app.readIDParam(r, "id")
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
@@ -121,6 +186,7 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	var input struct {
+		SKU         *string     `json:"sku"`
 		Name        *string     `json:"name"`
 		Description *string     `json:"description"`
 		Price       *data.Price `json:"price"`
@@ -128,6 +194,19 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 		Stock       *int32      `json:"stock"`
 		Category    []string    `json:"category"`
 		UpdatedAt   *time.Time  `json:"updated_at"`
+		// Kept raw so that null (turn the warning off, or clear the schedule or sale)
+		// can be told apart from leaving them out.
+		LowStockThreshold json.RawMessage `json:"low_stock_threshold"`
+		Status            *string         `json:"status"`
+		PublishAt         json.RawMessage `json:"publish_at"`
+		UnpublishAt       json.RawMessage `json:"unpublish_at"`
+		CompareAtPrice    json.RawMessage `json:"compare_at_price"`
+		SalePrice         json.RawMessage `json:"sale_price"`
+		SaleStartsAt      json.RawMessage `json:"sale_starts_at"`
+		SaleEndsAt        json.RawMessage `json:"sale_ends_at"`
+		BrandID           json.RawMessage `json:"brand_id"`
+		// Attributes are merged into the product's: a null value removes one.
+		Attributes map[string]any `json:"attributes"`
 	}
 
 	err = app.readJSON(w, r, &input)
@@ -136,6 +215,9 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if input.SKU != nil {
+		product.SKU = *input.SKU
+	}
 	if input.Name != nil {
 		product.Name = *input.Name
 	}
@@ -157,22 +239,110 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 	if input.UpdatedAt != nil {
 		product.UpdatedAt = *input.UpdatedAt
 	}
+	if input.LowStockThreshold != nil {
+		if err := readNullableField(input.LowStockThreshold, "low_stock_threshold", &product.LowStockThreshold); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.PublishAt != nil {
+		if err := readNullableField(input.PublishAt, "publish_at", &product.PublishAt); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.UnpublishAt != nil {
+		if err := readNullableField(input.UnpublishAt, "unpublish_at", &product.UnpublishAt); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.CompareAtPrice != nil {
+		if err := readNullableField(input.CompareAtPrice, "compare_at_price", &product.CompareAtPrice); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.SalePrice != nil {
+		if err := readNullableField(input.SalePrice, "sale_price", &product.SalePrice); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.SaleStartsAt != nil {
+		if err := readNullableField(input.SaleStartsAt, "sale_starts_at", &product.SaleStartsAt); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.SaleEndsAt != nil {
+		if err := readNullableField(input.SaleEndsAt, "sale_ends_at", &product.SaleEndsAt); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.BrandID != nil {
+		if err := readNullableField(input.BrandID, "brand_id", &product.BrandID); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	for key, value := range input.Attributes {
+		if value == nil {
+			delete(product.Attributes, key)
+			continue
+		}
+		if product.Attributes == nil {
+			product.Attributes = make(map[string]any)
+		}
+		product.Attributes[key] = value
+	}
 
 	v := validator.New()
 
-	if data.ValidateProduct(v, product); !v.Valid() {
+	if input.Status != nil && *input.Status != product.Status {
+		data.ValidateStatusChange(v, product.Status, *input.Status)
+		product.Status = *input.Status
+		app.holdForReview(product, user)
+	}
+	if input.UnpublishAt != nil && product.UnpublishAt != nil {
+		v.Check(product.UnpublishAt.After(time.Now()), "unpublish_at", "must be in the future")
+	}
+
+	if input.Category != nil {
+		err = app.resolveProductCategories(product, v)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	definitions, err := app.productAttributeDefinitions(product)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateProduct(v, product, definitions); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Products.Update(product)
+	err = app.models.Products.Update(product, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "is already used by another of your products")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownBrand):
+			v.AddError("brand_id", "brand does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
+		return
 	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"product": product}, nil)
@@ -182,7 +352,7 @@ func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Requ
 }
 
 func (app *application) deleteProductHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.readIDParam(r)
+	id, err := app.readIDParam(r, "id")
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
@@ -205,12 +375,8 @@ func (app *application) deleteProductHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if product.UserId != user.ID {
-		app.notPermittedResponse(w, r)
-		return
-	}
-
-	err = app.models.Products.Delete(id)
+	// Products are archived rather than deleted, since orders and reviews refer to them.
+	err = app.models.Products.Archive(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -221,7 +387,7 @@ func (app *application) deleteProductHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"message": "product deleted successfully"}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "product archived successfully"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -229,10 +395,18 @@ func (app *application) deleteProductHandler(w http.ResponseWriter, r *http.Requ
 }
 
 func (app *application) listProductHandler(w http.ResponseWriter, r *http.Request) {
+	app.listProducts(w, r, data.ProductSearch{})
+}
+
+// listProducts writes a page of the product list, filtered, sorted and paged by the
+// query string. scope holds the criteria fixed by the route: its SellerID limits the
+// list to one seller's products, and its Brands to one brand's.
+func (app *application) listProducts(w http.ResponseWriter, r *http.Request, scope data.ProductSearch) {
 	var input struct {
-		Name     string
-		Category []string
+		data.ProductSearch
 		data.Filters
+		Facets       []string
+		PriceBuckets []float64
 	}
 
 	v := validator.New()
@@ -240,29 +414,227 @@ func (app *application) listProductHandler(w http.ResponseWriter, r *http.Reques
 	qs := r.URL.Query()
 
 	input.Name = app.readString(qs, "name", "")
+	input.Query = strings.TrimSpace(app.readString(qs, "q", ""))
+	input.MinPrice = app.readFloat(qs, "min_price", v)
+	input.MaxPrice = app.readFloat(qs, "max_price", v)
+	input.InStock = app.readBool(qs, "in_stock", false, v)
+	input.Fuzzy = app.readBool(qs, "fuzzy", false, v)
+	input.OnSale = app.readBool(qs, "on_sale", false, v)
+	input.Facets = app.readCSV(qs, "facets", []string{})
+	input.PriceBuckets = app.readFloatCSV(qs, "price_buckets", app.config.facets.priceBuckets, v)
 	input.Category = app.readCSV(qs, "category", []string{})
+	for i := range input.Category {
+		input.Category[i] = data.Slugify(input.Category[i])
+	}
+	input.Sizes = app.readCSV(qs, "size", []string{})
+	input.Colours = app.readCSV(qs, "colour", []string{})
+	input.Attributes = app.readAttributeFilters(qs)
+	input.Brands = app.readCSV(qs, "brand", []string{})
+	for i := range input.Brands {
+		input.Brands[i] = data.Slugify(input.Brands[i])
+	}
+	input.SellerID = scope.SellerID
+	if scope.Brands != nil {
+		input.Brands = scope.Brands
+	}
+
+	// Shoppers only see the published catalogue. mine=true lists the seller's own
+	// products in every status instead, and moderators can list everything awaiting
+	// review with status=pending_review.
+	user := app.contextGetUser(r)
+	mine := app.readBool(qs, "mine", false, v)
+	input.Status = app.readString(qs, "status", "")
+	switch {
+	case mine:
+		if user.IsAnonymous() {
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+		input.OwnerID = user.ID
+	case input.Status == data.ProductPendingReview && user.Permissions.Include("products:moderate"):
+		input.ReviewQueue = true
+	case input.Status != "" && input.Status != data.ProductPublished:
+		v.AddError("status", "must be published unless mine=true is given")
+	}
 
+	// cursor=next_cursor carries on from a previous page in place of page. Counting
+	// every match is optional: by default only page-numbered lists do it.
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Cursor = app.readString(qs, "cursor", "")
+	input.Filters.WithTotal = app.readBool(qs, "total", input.Filters.Cursor == "", v)
 
 	input.Filters.Sort = app.readString(qs, "sort", "id")
 
-	input.Filters.SortSafelist = []string{"id", "name", "price", "created_at", "-id", "-name", "-price", "-created_at", "category"}
+	input.Filters.SortSafelist = []string{"id", "name", "price", "created_at", "-id", "-name", "-price", "-created_at", "category", "relevance", "rating", "-rating", "discount", "-discount"}
 
+	data.ValidateProductSearch(v, input.ProductSearch, input.Filters)
+	data.ValidateFacets(v, input.Facets, input.PriceBuckets)
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	products, metadata, err := app.models.Products.GetAll(input.Name, input.Category, input.Filters)
+	products, metadata, err := app.models.Products.GetAll(input.ProductSearch, input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"products": products, "metadata": metadata}, nil)
+	// If the full-text search found nothing the words are probably misspelled, so try
+	// again matching product names by similarity. The response says so, and clients
+	// pass fuzzy=true to fetch the following pages.
+	if input.Query != "" && !input.Fuzzy && len(products) == 0 && input.Page == 1 && input.Cursor == "" {
+		input.Fuzzy = true
+		products, metadata, err = app.models.Products.GetAll(input.ProductSearch, input.Filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	env := envelope{"products": products, "metadata": metadata}
+	if input.Query != "" {
+		env["fuzzy"] = input.Fuzzy
+	}
+
+	if len(input.Facets) > 0 {
+		facets, err := app.models.Products.GetFacets(input.ProductSearch, input.Facets, input.PriceBuckets)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["facets"] = facets
+	}
+
+	// Lists only get an ETag: a product dropping off a page doesn't make any of the
+	// others newer, so no date could show that the page had changed.
+	etag, err := productListETag(products, time.Now(), envelope{"metadata": env["metadata"], "fuzzy": env["fuzzy"], "facets": env["facets"]})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if app.notModified(w, r, etag, time.Time{}, app.config.httpCache.list) {
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+
+}
+
+// productFromRequest loads the product named in the URL. If owned is true it also
+// checks that the current user is the product's seller; otherwise products that aren't
+// visible to the user are treated as not found. It writes the error response itself
+// and returns nil on failure.
+func (app *application) productFromRequest(w http.ResponseWriter, r *http.Request, owned bool) *data.Product {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	product, err := app.models.Products.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	user := app.contextGetUser(r)
+	if owned && product.UserId != user.ID {
+		app.notPermittedResponse(w, r)
+		return nil
+	}
+	if !owned && !app.canSeeProduct(user, product) {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	return product
+}
+
+// moderateProductHandler approves a product that is awaiting review, publishing it, or
+// rejects it, sending it back to its seller as a draft.
+func (app *application) moderateProductHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	var input struct {
+		Approve *bool `json:"approve"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(input.Approve != nil, "approve", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if product.Status != data.ProductPendingReview {
+		app.errorResponse(w, r, http.StatusConflict, "the product is not awaiting review")
+		return
+	}
+
+	product.Status = data.ProductDraft
+	if *input.Approve {
+		product.Status = data.ProductPublished
+	}
+
+	err = app.models.Products.Update(product, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"product": product}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
+}
+
+// holdForReview sends a product its seller is publishing to pending_review instead,
+// when the service is configured to require products to be approved first. Moderators
+// can publish their own products directly.
+func (app *application) holdForReview(product *data.Product, user *data.User) {
+	if app.config.products.requireReview && product.Status == data.ProductPublished &&
+		!user.Permissions.Include("products:moderate") {
+		product.Status = data.ProductPendingReview
+	}
+}
 
+// canSeeProduct reports whether the user may see a product that isn't visible to
+// shoppers: sellers can always see their own, and moderators what awaits review.
+func (app *application) canSeeProduct(user *data.User, product *data.Product) bool {
+	if product.Visible(time.Now()) || product.UserId == user.ID {
+		return true
+	}
+	return product.Status == data.ProductPendingReview && user.Permissions.Include("products:moderate")
+}
+
+// readNullableField decodes a field that was kept raw so that null could be told apart
+// from leaving it out.
+func readNullableField(raw json.RawMessage, name string, dst interface{}) error {
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fmt.Errorf("body contains incorrect JSON type for %q", name)
+	}
+	return nil
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// similarProductsHandler lists products in the same categories as the product, or
+// with similar names, best matches first.
+func (app *application) similarProductsHandler(w http.ResponseWriter, r *http.Request) {
+	app.recommendProducts(w, r, app.models.Recommendations.GetSimilar)
+}
+
+// boughtTogetherHandler lists the products most often ordered along with the product.
+func (app *application) boughtTogetherHandler(w http.ResponseWriter, r *http.Request) {
+	app.recommendProducts(w, r, app.models.Recommendations.GetBoughtTogether)
+}
+
+func (app *application) recommendProducts(w http.ResponseWriter, r *http.Request, recommend func(int64, int) ([]*data.Product, error)) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	v := validator.New()
+
+	limit := app.readInt(r.URL.Query(), "limit", 10, v)
+
+	if data.ValidateRecommendationLimit(v, limit); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	products, err := recommend(product.ID, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"products": products}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
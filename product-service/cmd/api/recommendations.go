@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+)
+
+// coPurchaseBatchSize is how many order items are read from order-service at a time.
+const coPurchaseBatchSize = 1000
+
+// startCoPurchaseSync brings the bought-together counts up to date with order-service
+// on startup and then every recommendations-interval, until the server shuts down.
+// Without a service token it isn't started, and bought-together lists stay as they are.
+func (app *application) startCoPurchaseSync() {
+	if app.config.recommendations.serviceToken == "" {
+		app.logger.PrintInfo("bought-together sync disabled: no recommendations service token", nil)
+		return
+	}
+
+	app.runEvery(app.config.recommendations.interval, true, app.syncCoPurchases)
+}
+
+// syncCoPurchases adds the orders placed since the last sync to the bought-together
+// counts, a batch at a time, until it has caught up or the server starts shutting
+// down. Only new order items are read, and each batch is committed with its cursor, so
+// the next sync carries on where this one stopped.
+func (app *application) syncCoPurchases() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.PrintError(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	start, err := app.models.Recommendations.GetCoPurchaseCursor()
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"component": "recommendations"})
+		return
+	}
+
+	cursor := start
+	for {
+		pairs, next, err := app.getCoPurchases(cursor, coPurchaseBatchSize)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"component": "recommendations"})
+			return
+		}
+		if next <= cursor {
+			break
+		}
+
+		err = app.models.Recommendations.AddCoPurchases(pairs, cursor, next)
+		if err != nil {
+			// Another instance has synced these orders already; it will carry on.
+			if !errors.Is(err, data.ErrEditConflict) {
+				app.logger.PrintError(err, map[string]string{"component": "recommendations"})
+			}
+			return
+		}
+		cursor = next
+
+		if app.shuttingDown() {
+			break
+		}
+	}
+
+	if cursor > start {
+		app.logger.PrintInfo("synced bought-together recommendations", map[string]string{
+			"last_order_item_id": fmt.Sprintf("%d", cursor),
+		})
+	}
+}
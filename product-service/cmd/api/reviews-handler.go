@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+func (app *application) listReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	var input struct {
+		Rating int
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Rating = app.readInt(qs, "rating", 0, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"created_at", "-created_at", "rating", "-rating"}
+
+	v.Check(input.Rating >= 0 && input.Rating <= 5, "rating", "must be between 1 and 5")
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reviews, metadata, err := app.models.Reviews.GetAllForProduct(product.ID, int16(input.Rating), input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"reviews":      reviews,
+		"metadata":     metadata,
+		"rating":       product.Rating,
+		"review_count": product.ReviewCount,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createReviewHandler adds the user's review of a product. Only users with a delivered
+// order containing the product may review it, which is checked with order-service.
+func (app *application) createReviewHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if product.UserId == user.ID {
+		app.errorResponse(w, r, http.StatusForbidden, "you can't review your own product")
+		return
+	}
+
+	var input struct {
+		Rating    int16    `json:"rating"`
+		Title     string   `json:"title"`
+		Body      string   `json:"body"`
+		PhotoURLs []string `json:"photo_urls"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	review := &data.Review{
+		ProductID: product.ID,
+		UserID:    user.ID,
+		Rating:    input.Rating,
+		Title:     strings.TrimSpace(input.Title),
+		Body:      strings.TrimSpace(input.Body),
+		PhotoURLs: input.PhotoURLs,
+	}
+	if review.PhotoURLs == nil {
+		review.PhotoURLs = []string{}
+	}
+
+	v := validator.New()
+
+	if data.ValidateReview(v, review); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	review.OrderID, err = app.getDeliveredPurchase(product.ID, r.Header.Get("Authorization"))
+	if err != nil {
+		switch {
+		case errors.Is(err, errPurchaseNotFound):
+			app.errorResponse(w, r, http.StatusForbidden, "only customers who have received this product can review it")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Reviews.Insert(review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateReview):
+			app.errorResponse(w, r, http.StatusConflict, "you have already reviewed this product")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/products/%d/reviews/%d", product.ID, review.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"review": review}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	review := app.reviewFromRequest(w, r)
+	if review == nil {
+		return
+	}
+
+	if review.UserID != app.contextGetUser(r).ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Rating    *int16   `json:"rating"`
+		Title     *string  `json:"title"`
+		Body      *string  `json:"body"`
+		PhotoURLs []string `json:"photo_urls"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Rating != nil {
+		review.Rating = *input.Rating
+	}
+	if input.Title != nil {
+		review.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Body != nil {
+		review.Body = strings.TrimSpace(*input.Body)
+	}
+	if input.PhotoURLs != nil {
+		review.PhotoURLs = input.PhotoURLs
+	}
+
+	v := validator.New()
+
+	if data.ValidateReview(v, review); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reviews.Update(review)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"review": review}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	review := app.reviewFromRequest(w, r)
+	if review == nil {
+		return
+	}
+
+	if review.UserID != app.contextGetUser(r).ID {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err := app.models.Reviews.Delete(review.ID, review.ProductID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "review deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// replyReviewHandler sets the seller's public reply to a review of their product.
+func (app *application) replyReviewHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	review := app.reviewFromRequest(w, r)
+	if review == nil {
+		return
+	}
+
+	var input struct {
+		Reply string `json:"reply"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Reply = strings.TrimSpace(input.Reply)
+
+	v := validator.New()
+
+	if data.ValidateReviewReply(v, input.Reply); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reviews.Reply(review, input.Reply)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"review": review}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reportReviewHandler records an abuse report against a review.
+func (app *application) reportReviewHandler(w http.ResponseWriter, r *http.Request) {
+	review := app.reviewFromRequest(w, r)
+	if review == nil {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Reason = strings.TrimSpace(input.Reason)
+
+	v := validator.New()
+
+	if data.ValidateReviewReport(v, input.Reason); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Reviews.Report(review.ID, app.contextGetUser(r).ID, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateReport):
+			app.errorResponse(w, r, http.StatusConflict, "you have already reported this review")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"message": "thanks, the review has been reported"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reviewFromRequest loads the review named in the URL. It writes the error response
+// itself and returns nil on failure.
+func (app *application) reviewFromRequest(w http.ResponseWriter, r *http.Request) *data.Review {
+	productID, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	reviewID, err := app.readIDParam(r, "review_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	review, err := app.models.Reviews.Get(reviewID, productID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return review
+}
@@ -4,6 +4,7 @@ import (
 	"expvar"
 	"net/http"
 
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/storage"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -20,13 +21,81 @@ func (app *application) routes() http.Handler {
 
 	// Public routes
 	router.MethodFunc(http.MethodGet, "/v1/products", app.listProductHandler)
+	router.MethodFunc(http.MethodGet, suggestPath, app.suggestRateLimit(app.suggestProductsHandler))
 	router.MethodFunc(http.MethodGet, "/v1/products/{id}", app.showProductHandler)
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/variants", app.listVariantsHandler)
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/variants/{variant_id}", app.showVariantHandler)
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/images", app.listProductImagesHandler)
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/reviews", app.listReviewsHandler)
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/price-history", app.listPriceHistoryHandler)
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/similar", app.similarProductsHandler)
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/bought-together", app.boughtTogetherHandler)
+	router.MethodFunc(http.MethodGet, "/v1/wishlists/shared/{token}", app.showSharedWishlistHandler)
+	router.MethodFunc(http.MethodGet, "/v1/sellers/{slug}", app.showSellerHandler)
+	router.MethodFunc(http.MethodGet, "/v1/sellers/{slug}/products", app.listSellerProductsHandler)
+	router.MethodFunc(http.MethodGet, "/v1/brands", app.listBrandsHandler)
+	router.MethodFunc(http.MethodGet, "/v1/brands/{slug}", app.showBrandHandler)
+	router.MethodFunc(http.MethodGet, "/v1/brands/{slug}/products", app.listBrandProductsHandler)
+	router.MethodFunc(http.MethodGet, "/v1/categories", app.listCategoriesHandler)
+	router.MethodFunc(http.MethodGet, "/v1/categories/{id}", app.showCategoryHandler)
+	router.MethodFunc(http.MethodGet, "/v1/categories/{id}/attributes", app.listCategoryAttributesHandler)
 
 	// Protected routes - require activated user
 	// app.requireActivatedUser()
 	router.MethodFunc(http.MethodPost, "/v1/products", app.requireActivatedUser(app.createProductHandler))
+	router.MethodFunc(http.MethodPost, "/v1/products/import", app.requireActivatedUser(app.importProductsHandler))
+	router.MethodFunc(http.MethodGet, "/v1/products/export", app.requireActivatedUser(app.exportProductsHandler))
+	router.MethodFunc(http.MethodGet, "/v1/imports/{id}", app.requireActivatedUser(app.showImportHandler))
 	router.MethodFunc(http.MethodPatch, "/v1/products/{id}", app.requireActivatedUser(app.updateProductHandler))
 	router.MethodFunc(http.MethodDelete, "/v1/products/{id}", app.requireActivatedUser(app.deleteProductHandler))
+	router.MethodFunc(http.MethodPost, "/v1/products/{id}/variants", app.requireActivatedUser(app.createVariantHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/products/{id}/variants/{variant_id}", app.requireActivatedUser(app.updateVariantHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/products/{id}/variants/{variant_id}", app.requireActivatedUser(app.deleteVariantHandler))
+	router.MethodFunc(http.MethodPost, "/v1/products/{id}/images", app.requireActivatedUser(app.uploadProductImageHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/products/{id}/images/{image_id}", app.requireActivatedUser(app.updateProductImageHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/products/{id}/images/{image_id}", app.requireActivatedUser(app.deleteProductImageHandler))
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/stock-movements", app.requireActivatedUser(app.listStockMovementsHandler))
+	router.MethodFunc(http.MethodPost, "/v1/products/{id}/stock-movements", app.requireActivatedUser(app.createStockMovementHandler))
+	router.MethodFunc(http.MethodGet, "/v1/products/{id}/stock-movements/reconciliation", app.requireActivatedUser(app.reconcileStockHandler))
+	router.MethodFunc(http.MethodPost, "/v1/products/{id}/stock-subscriptions", app.requireActivatedUser(app.createStockSubscriptionHandler))
+	router.MethodFunc(http.MethodGet, "/v1/stock-subscriptions", app.requireActivatedUser(app.listStockSubscriptionsHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/stock-subscriptions/{id}", app.requireActivatedUser(app.deleteStockSubscriptionHandler))
+	router.MethodFunc(http.MethodPost, "/v1/products/{id}/reviews", app.requireActivatedUser(app.createReviewHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/products/{id}/reviews/{review_id}", app.requireActivatedUser(app.updateReviewHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/products/{id}/reviews/{review_id}", app.requireActivatedUser(app.deleteReviewHandler))
+	router.MethodFunc(http.MethodPut, "/v1/products/{id}/reviews/{review_id}/reply", app.requireActivatedUser(app.replyReviewHandler))
+	router.MethodFunc(http.MethodPost, "/v1/products/{id}/reviews/{review_id}/reports", app.requireActivatedUser(app.reportReviewHandler))
+	router.MethodFunc(http.MethodGet, "/v1/wishlists", app.requireActivatedUser(app.listWishlistsHandler))
+	router.MethodFunc(http.MethodPost, "/v1/wishlists", app.requireActivatedUser(app.createWishlistHandler))
+	router.MethodFunc(http.MethodGet, "/v1/wishlists/{id}", app.requireActivatedUser(app.showWishlistHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/wishlists/{id}", app.requireActivatedUser(app.updateWishlistHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/wishlists/{id}", app.requireActivatedUser(app.deleteWishlistHandler))
+	router.MethodFunc(http.MethodPost, "/v1/wishlists/{id}/items", app.requireActivatedUser(app.addWishlistItemHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/wishlists/{id}/items/{item_id}", app.requireActivatedUser(app.updateWishlistItemHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/wishlists/{id}/items/{item_id}", app.requireActivatedUser(app.deleteWishlistItemHandler))
+	router.MethodFunc(http.MethodGet, "/v1/sellers/me", app.requireActivatedUser(app.showMySellerHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/sellers/me", app.requireActivatedUser(app.updateMySellerHandler))
+
+	// Admin routes - require the categories:admin permission
+	router.MethodFunc(http.MethodPost, "/v1/categories", app.requirePermission("categories:admin", app.createCategoryHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/categories/{id}", app.requirePermission("categories:admin", app.updateCategoryHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/categories/{id}", app.requirePermission("categories:admin", app.deleteCategoryHandler))
+	router.MethodFunc(http.MethodPost, "/v1/categories/{id}/attributes", app.requirePermission("categories:admin", app.createCategoryAttributeHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/attributes/{id}", app.requirePermission("categories:admin", app.updateAttributeHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/attributes/{id}", app.requirePermission("categories:admin", app.deleteAttributeHandler))
+
+	// Admin routes - require the brands:admin permission
+	router.MethodFunc(http.MethodPost, "/v1/brands", app.requirePermission("brands:admin", app.createBrandHandler))
+	router.MethodFunc(http.MethodPatch, "/v1/brands/{slug}", app.requirePermission("brands:admin", app.updateBrandHandler))
+	router.MethodFunc(http.MethodDelete, "/v1/brands/{slug}", app.requirePermission("brands:admin", app.deleteBrandHandler))
+
+	// Moderator routes - require the products:moderate permission
+	router.MethodFunc(http.MethodPost, "/v1/products/{id}/moderation", app.requirePermission("products:moderate", app.moderateProductHandler))
+
+	// Uploaded images
+	if local, ok := app.storage.(*storage.Local); ok {
+		router.Method(http.MethodGet, "/uploads/*", http.StripPrefix("/uploads", local.Handler()))
+	}
 
 	router.Method(http.MethodGet, "/debug/vars", expvar.Handler())
 
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+)
+
+// startProductScheduler applies products' publish_at and unpublish_at schedules every
+// schedule-interval, until the server shuts down.
+func (app *application) startProductScheduler() {
+	app.runEvery(app.config.products.scheduleInterval, false, app.applyProductSchedule)
+}
+
+func (app *application) applyProductSchedule() {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.PrintError(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	published, archived, err := app.models.Products.ApplySchedule()
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"component": "scheduler"})
+		return
+	}
+
+	if published > 0 || archived > 0 {
+		app.logger.PrintInfo("applied product schedules", map[string]string{
+			"published": fmt.Sprintf("%d", published),
+			"archived":  fmt.Sprintf("%d", archived),
+		})
+	}
+}
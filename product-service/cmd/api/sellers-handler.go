@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) showSellerHandler(w http.ResponseWriter, r *http.Request) {
+	seller := app.sellerFromRequest(w, r)
+	if seller == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"seller": seller}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listSellerProductsHandler lists a seller's products. It takes the same filters,
+// sorting and paging as the main product list.
+func (app *application) listSellerProductsHandler(w http.ResponseWriter, r *http.Request) {
+	seller := app.sellerFromRequest(w, r)
+	if seller == nil {
+		return
+	}
+
+	app.listProducts(w, r, data.ProductSearch{SellerID: seller.UserID})
+}
+
+func (app *application) showMySellerHandler(w http.ResponseWriter, r *http.Request) {
+	seller, err := app.models.Sellers.GetForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"seller": seller}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateMySellerHandler edits the user's storefront profile, creating it the first
+// time. A new profile's slug is made from its display name unless one is given.
+func (app *application) updateMySellerHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	created := false
+	seller, err := app.models.Sellers.GetForUser(user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			seller = &data.Seller{UserID: user.ID}
+			created = true
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	var input struct {
+		DisplayName  *string `json:"display_name"`
+		Slug         *string `json:"slug"`
+		Bio          *string `json:"bio"`
+		LogoURL      *string `json:"logo_url"`
+		ReturnPolicy *string `json:"return_policy"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.DisplayName != nil {
+		seller.DisplayName = strings.TrimSpace(*input.DisplayName)
+	}
+	if input.Slug != nil {
+		seller.Slug = *input.Slug
+	} else if created {
+		seller.Slug = data.Slugify(seller.DisplayName)
+	}
+	if input.Bio != nil {
+		seller.Bio = *input.Bio
+	}
+	if input.LogoURL != nil {
+		seller.LogoURL = *input.LogoURL
+	}
+	if input.ReturnPolicy != nil {
+		seller.ReturnPolicy = *input.ReturnPolicy
+	}
+
+	v := validator.New()
+
+	if data.ValidateSeller(v, seller); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		err = app.models.Sellers.Insert(seller)
+	} else {
+		err = app.models.Sellers.Update(seller)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSlug):
+			v.AddError("slug", "is already taken by another seller")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, status, envelope{"seller": seller}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// sellerFromRequest loads the seller whose slug is in the URL. It writes the error
+// response itself and returns nil on failure.
+func (app *application) sellerFromRequest(w http.ResponseWriter, r *http.Request) *data.Seller {
+	seller, err := app.models.Sellers.GetBySlug(chi.URLParam(r, "slug"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return seller
+}
@@ -58,6 +58,9 @@ func (app *application) serve() error {
 			shutdownError <- err
 		}
 
+		// Tell the periodic background workers to stop once their current run is done.
+		close(app.shutdown)
+
 		// Log a message to say that we're waiting for any background goroutines to
 		// complete their tasks.
 		app.logger.PrintInfo("completing background tasks", map[string]string{
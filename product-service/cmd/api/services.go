@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -45,10 +46,11 @@ func (app *application) getUserFromUserService(userID int64) (*data.User, error)
 	// Parse the response
 	var envelope struct {
 		User struct {
-			ID        int64  `json:"id"`
-			Email     string `json:"email"`
-			Name      string `json:"name"`
-			Activated bool   `json:"activated"`
+			ID          int64    `json:"id"`
+			Email       string   `json:"email"`
+			Name        string   `json:"name"`
+			Activated   bool     `json:"activated"`
+			Permissions []string `json:"permissions"`
 		} `json:"user"`
 	}
 
@@ -59,10 +61,11 @@ func (app *application) getUserFromUserService(userID int64) (*data.User, error)
 
 	// Convert to internal User type
 	user := &data.User{
-		ID:        envelope.User.ID,
-		Email:     envelope.User.Email,
-		Name:      envelope.User.Name,
-		Activated: envelope.User.Activated,
+		ID:          envelope.User.ID,
+		Email:       envelope.User.Email,
+		Name:        envelope.User.Name,
+		Activated:   envelope.User.Activated,
+		Permissions: envelope.User.Permissions,
 	}
 
 	return user, nil
@@ -114,3 +117,87 @@ func (app *application) invalidateUserCache(userID int64) {
 		})
 	}
 }
+
+var errPurchaseNotFound = errors.New("purchase not found")
+
+// getDeliveredPurchase asks order-service for the current user's delivered order that
+// contains the product, forwarding the user's own Authorization header. It returns
+// errPurchaseNotFound if the user has never received the product.
+func (app *application) getDeliveredPurchase(productID int64, authorization string) (int64, error) {
+	url := fmt.Sprintf("%s/v1/orders/purchases/%d", app.config.orderService.url, productID)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", authorization)
+
+	ctx, cancel := app.createRequestContext(3 * time.Second)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	resp, err := app.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("order-service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, errPurchaseNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("order-service returned status %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Purchase struct {
+			OrderID int64 `json:"order_id"`
+		} `json:"purchase"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&envelope)
+	if err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+
+	return envelope.Purchase.OrderID, nil
+}
+
+// getCoPurchases reads the product pairs completed by order items after afterID from
+// order-service, along with the afterID to read on from next time. It authenticates
+// with the recommendations service token.
+func (app *application) getCoPurchases(afterID int64, limit int) ([]data.CoPurchase, int64, error) {
+	url := fmt.Sprintf("%s/v1/orders/co-purchases?after_id=%d&limit=%d", app.config.orderService.url, afterID, limit)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+app.config.recommendations.serviceToken)
+
+	ctx, cancel := app.createRequestContext(5 * time.Second)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	resp, err := app.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("order-service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("order-service returned status %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		CoPurchases []data.CoPurchase `json:"co_purchases"`
+		NextAfterID int64             `json:"next_after_id"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&envelope)
+	if err != nil {
+		return nil, 0, fmt.Errorf("decode response: %w", err)
+	}
+
+	return envelope.CoPurchases, envelope.NextAfterID, nil
+}
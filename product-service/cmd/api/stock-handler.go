@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// listStockMovementsHandler returns a page of the product's stock ledger to its
+// seller. variant_id=0 limits it to the product's own stock.
+func (app *application) listStockMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	var input struct {
+		VariantID *int64
+		Reason    string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	if qs.Has("variant_id") {
+		variantID := int64(app.readInt(qs, "variant_id", 0, v))
+		v.Check(variantID >= 0, "variant_id", "must be zero or a positive integer")
+		input.VariantID = &variantID
+	}
+	input.Reason = app.readString(qs, "reason", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 50, v)
+	input.Filters.Sort = "-created_at"
+	input.Filters.SortSafelist = []string{"-created_at"}
+
+	if input.Reason != "" {
+		v.Check(input.Reason == data.StockInitial || validator.In(input.Reason, data.StockReasons...), "reason", "invalid reason")
+	}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movements, metadata, err := app.models.Stock.GetAllForProduct(product.ID, input.VariantID, input.Reason, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stock_movements": movements, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createStockMovementHandler lets the seller record a sale, restock, return or
+// adjustment, changing the stock by the given delta.
+func (app *application) createStockMovementHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	var input struct {
+		VariantID   *int64 `json:"variant_id"`
+		Delta       int32  `json:"delta"`
+		Reason      string `json:"reason"`
+		ReferenceID string `json:"reference_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	movement := &data.StockMovement{
+		ProductID:   product.ID,
+		VariantID:   input.VariantID,
+		Delta:       input.Delta,
+		Reason:      input.Reason,
+		ReferenceID: strings.TrimSpace(input.ReferenceID),
+		ActorID:     &user.ID,
+	}
+
+	v := validator.New()
+
+	if data.ValidateStockMovement(v, movement); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Stock.Record(movement)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("variant_id", "variant does not exist for this product")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInsufficientStock):
+			v.AddError("delta", "would take the stock below zero")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/products/%d/stock-movements", product.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"stock_movement": movement}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reconcileStockHandler checks that the product's stock, and each variant's, matches
+// the sum of its ledger entries.
+func (app *application) reconcileStockHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	results, err := app.models.Stock.Reconcile(product.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	balanced := true
+	for _, result := range results {
+		if !result.Balanced {
+			balanced = false
+			properties := map[string]string{
+				"product_id": fmt.Sprintf("%d", product.ID),
+				"stock":      fmt.Sprintf("%d", result.Stock),
+				"ledger":     fmt.Sprintf("%d", result.LedgerTotal),
+			}
+			if result.VariantID != nil {
+				properties["variant_id"] = fmt.Sprintf("%d", *result.VariantID)
+			}
+			app.logger.PrintError(errors.New("stock does not match the ledger"), properties)
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"balanced": balanced, "reconciliation": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
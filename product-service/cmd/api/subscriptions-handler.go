@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// createStockSubscriptionHandler asks to be notified when a sold-out product, or one of
+// its variants, is back in stock.
+func (app *application) createStockSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	var input struct {
+		VariantID *int64 `json:"variant_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	inStock := product.Stock > 0
+	if input.VariantID != nil {
+		variant, err := app.models.Variants.Get(*input.VariantID, product.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("variant_id", "variant does not exist for this product")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		inStock = variant.Stock > 0
+	} else {
+		variants, err := app.models.Variants.GetAllForProduct(product.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		for _, variant := range variants {
+			inStock = inStock || variant.Stock > 0
+		}
+	}
+
+	if inStock {
+		app.errorResponse(w, r, http.StatusConflict, "the product is in stock")
+		return
+	}
+
+	sub := &data.StockSubscription{
+		ProductID: product.ID,
+		VariantID: input.VariantID,
+		UserID:    app.contextGetUser(r).ID,
+	}
+
+	err = app.models.Notifications.InsertSubscription(sub)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSubscription):
+			app.errorResponse(w, r, http.StatusConflict, "you will already be notified when this is back in stock")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"subscription": sub}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listStockSubscriptionsHandler returns the user's back-in-stock requests that haven't
+// been used yet.
+func (app *application) listStockSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	subs, err := app.models.Notifications.GetSubscriptionsForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"subscriptions": subs}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteStockSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Notifications.DeleteSubscription(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "subscription deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// suggestPath is exempt from the main rate limit; see rateLimit.
+const suggestPath = "/v1/products/suggest"
+
+// suggestProductsHandler returns autocomplete suggestions for a partially typed search.
+// If the lookup can't finish within the configured time limit an empty list is
+// returned rather than holding up the shopper's typing.
+func (app *application) suggestProductsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	q := strings.TrimSpace(app.readString(qs, "q", ""))
+	limit := app.readInt(qs, "limit", 10, v)
+
+	v.Check(len(q) >= 2, "q", "must be at least 2 characters long")
+	v.Check(len(q) <= 100, "q", "must not exceed 100 characters")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 20, "limit", "must be a maximum of 20")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), app.config.suggest.timeout)
+	defer cancel()
+
+	suggestions, err := app.models.Products.Suggest(ctx, q, limit)
+	if err != nil {
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.logger.PrintInfo("autocomplete timed out", map[string]string{"q": q})
+		suggestions = []data.Suggestion{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
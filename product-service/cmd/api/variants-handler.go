@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// variantFromRequest loads the variant named in the URL, making sure it belongs to
+// the product. It writes the error response itself and returns nil on failure.
+func (app *application) variantFromRequest(w http.ResponseWriter, r *http.Request, product *data.Product) *data.ProductVariant {
+	variantID, err := app.readIDParam(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	variant, err := app.models.Variants.Get(variantID, product.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return variant
+}
+
+func (app *application) listVariantsHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	variants, err := app.models.Variants.GetAllForProduct(product.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variants": variants}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showVariantHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, false)
+	if product == nil {
+		return
+	}
+
+	variant := app.variantFromRequest(w, r, product)
+	if variant == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"variant": variant}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createVariantHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		SKU      string            `json:"sku"`
+		Options  map[string]string `json:"options"`
+		Price    *data.Price       `json:"price"`
+		Stock    int32             `json:"stock"`
+		ImageUrl *string           `json:"image_url"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	variant := &data.ProductVariant{
+		ProductID: product.ID,
+		SKU:       input.SKU,
+		Options:   data.NormalizeOptions(input.Options),
+		Price:     input.Price,
+		Stock:     input.Stock,
+		ImageUrl:  input.ImageUrl,
+	}
+
+	v := validator.New()
+
+	if data.ValidateVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Variants.Insert(variant, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "this product already has a variant with this SKU")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/products/%d/variants/%d", product.ID, variant.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"variant": variant}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateVariantHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	variant := app.variantFromRequest(w, r, product)
+	if variant == nil {
+		return
+	}
+
+	var input struct {
+		SKU      *string           `json:"sku"`
+		Options  map[string]string `json:"options"`
+		Stock    *int32            `json:"stock"`
+		ImageUrl *string           `json:"image_url"`
+		// Kept raw so that null can clear the price back to the product's.
+		Price json.RawMessage `json:"price"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.SKU != nil {
+		variant.SKU = *input.SKU
+	}
+	if input.Options != nil {
+		variant.Options = data.NormalizeOptions(input.Options)
+	}
+	if input.Price != nil {
+		if err := readNullableField(input.Price, "price", &variant.Price); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	if input.Stock != nil {
+		variant.Stock = *input.Stock
+	}
+	if input.ImageUrl != nil {
+		variant.ImageUrl = input.ImageUrl
+	}
+
+	v := validator.New()
+
+	if data.ValidateVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Variants.Update(variant, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateSKU):
+			v.AddError("sku", "this product already has a variant with this SKU")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variant": variant}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteVariantHandler(w http.ResponseWriter, r *http.Request) {
+	product := app.productFromRequest(w, r, true)
+	if product == nil {
+		return
+	}
+
+	variantID, err := app.readIDParam(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Variants.Delete(variantID, product.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "variant deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
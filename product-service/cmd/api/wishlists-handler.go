@@ -0,0 +1,389 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/data"
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) listWishlistsHandler(w http.ResponseWriter, r *http.Request) {
+	wishlists, err := app.models.Wishlists.GetAllForUser(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"wishlists": wishlists}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	wishlist := &data.Wishlist{
+		UserID: app.contextGetUser(r).ID,
+		Name:   strings.TrimSpace(input.Name),
+	}
+
+	v := validator.New()
+
+	if data.ValidateWishlist(v, wishlist); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if err := app.setWishlistSharing(wishlist, input.Public); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Wishlists.Insert(wishlist)
+	if err != nil {
+		app.wishlistWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/wishlists/%d", wishlist.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"wishlist": wishlist}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showWishlistHandler returns one of the user's wishlists with its items.
+func (app *application) showWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	wishlist := app.wishlistFromRequest(w, r)
+	if wishlist == nil {
+		return
+	}
+
+	var err error
+	wishlist.Items, err = app.models.Wishlists.GetItems(wishlist.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"wishlist": wishlist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showSharedWishlistHandler returns a shared wishlist to anyone with its link.
+func (app *application) showSharedWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	wishlist, err := app.models.Wishlists.GetByShareToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	wishlist.Items, err = app.models.Wishlists.GetItems(wishlist.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"wishlist": wishlist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateWishlistHandler renames a wishlist or turns its share link on or off. Turning
+// sharing off and on again creates a new link, so old links stop working.
+func (app *application) updateWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	wishlist := app.wishlistFromRequest(w, r)
+	if wishlist == nil {
+		return
+	}
+
+	var input struct {
+		Name   *string `json:"name"`
+		Public *bool   `json:"public"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		wishlist.Name = strings.TrimSpace(*input.Name)
+	}
+
+	v := validator.New()
+
+	if data.ValidateWishlist(v, wishlist); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.Public != nil {
+		if err := app.setWishlistSharing(wishlist, *input.Public); err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.models.Wishlists.Update(wishlist)
+	if err != nil {
+		app.wishlistWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"wishlist": wishlist}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Wishlists.Delete(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "wishlist deleted successfully"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addWishlistItemHandler(w http.ResponseWriter, r *http.Request) {
+	wishlist := app.wishlistFromRequest(w, r)
+	if wishlist == nil {
+		return
+	}
+
+	var input struct {
+		ProductID int64  `json:"product_id"`
+		Note      string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Note = strings.TrimSpace(input.Note)
+
+	v := validator.New()
+
+	v.Check(input.ProductID > 0, "product_id", "must be a positive integer")
+	if data.ValidateWishlistNote(v, input.Note); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	item, err := app.models.Wishlists.AddItem(wishlist.ID, input.ProductID, input.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("product_id", "product does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateWishlistItem):
+			app.errorResponse(w, r, http.StatusConflict, "the product is already on this wishlist")
+		case errors.Is(err, data.ErrTooManyWishlistItems):
+			v.AddError("product_id", fmt.Sprintf("a wishlist can't have more than %d items", data.MaxWishlistItems))
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/wishlists/%d/items/%d", wishlist.ID, item.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"item": item}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateWishlistItemHandler(w http.ResponseWriter, r *http.Request) {
+	wishlist := app.wishlistFromRequest(w, r)
+	if wishlist == nil {
+		return
+	}
+
+	itemID, err := app.readIDParam(r, "item_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	item, err := app.models.Wishlists.GetItem(itemID, wishlist.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Note *string `json:"note"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Note != nil {
+		item.Note = strings.TrimSpace(*input.Note)
+	}
+
+	v := validator.New()
+
+	if data.ValidateWishlistNote(v, item.Note); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Wishlists.UpdateItemNote(item)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"item": item}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWishlistItemHandler(w http.ResponseWriter, r *http.Request) {
+	wishlist := app.wishlistFromRequest(w, r)
+	if wishlist == nil {
+		return
+	}
+
+	itemID, err := app.readIDParam(r, "item_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Wishlists.DeleteItem(itemID, wishlist.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "item removed from wishlist"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// wishlistFromRequest loads the current user's wishlist named in the URL. Other users'
+// wishlists are reported as not found. It writes the error response itself and returns
+// nil on failure.
+func (app *application) wishlistFromRequest(w http.ResponseWriter, r *http.Request) *data.Wishlist {
+	id, err := app.readIDParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil
+	}
+
+	wishlist, err := app.models.Wishlists.Get(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil
+	}
+
+	return wishlist
+}
+
+// setWishlistSharing gives the wishlist a share token if it is public and doesn't
+// have one yet, or removes it if it is private.
+func (app *application) setWishlistSharing(wishlist *data.Wishlist, public bool) error {
+	if !public {
+		wishlist.ShareToken = nil
+		return nil
+	}
+	if wishlist.ShareToken != nil {
+		return nil
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	wishlist.ShareToken = &token
+	return nil
+}
+
+// wishlistWriteErrorResponse turns the errors returned when saving a wishlist into the
+// matching response.
+func (app *application) wishlistWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrEditConflict):
+		app.editConflictResponse(w, r)
+	case errors.Is(err, data.ErrDuplicateWishlist):
+		v.AddError("name", "you already have a wishlist with this name")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,452 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateAttribute = errors.New("duplicate attribute")
+	ErrAttributeInUse     = errors.New("attribute in use")
+	attributeKeyRX        = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// The types an attribute can have. Enum values must be one of the definition's
+// options; the others are JSON numbers, booleans and strings.
+const (
+	AttributeEnum    = "enum"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+	AttributeText    = "text"
+)
+
+var AttributeTypes = []string{AttributeEnum, AttributeNumber, AttributeBoolean, AttributeText}
+
+// MaxAttributeFilters is the most attr.* filters one product list can have.
+const MaxAttributeFilters = 10
+
+// AttributeDefinition is an attribute that products in a category, or any of its
+// subcategories, can have, such as "material" or "sleeve". Its key is unique along
+// each branch of the taxonomy, so a product's categories can't define it twice over.
+type AttributeDefinition struct {
+	ID         int64 `json:"id"`
+	CategoryID int64 `json:"category_id"`
+	// Category is the slug of the category the attribute is defined on, which for
+	// inherited definitions isn't the one they were listed for.
+	Category  string    `json:"category"`
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Options   []string  `json:"options,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int32     `json:"version"`
+}
+
+func ValidateAttributeDefinition(v *validator.Validator, def *AttributeDefinition) {
+	v.Check(def.Key != "", "key", "must be provided")
+	v.Check(len(def.Key) <= 50, "key", "must not exceed 50 characters")
+	v.Check(validator.Matches(def.Key, attributeKeyRX), "key", "must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+
+	v.Check(strings.TrimSpace(def.Name) != "", "name", "must be provided")
+	v.Check(len(def.Name) <= 100, "name", "must not exceed 100 characters")
+
+	v.Check(validator.In(def.Type, AttributeTypes...), "type", "must be one of enum, number, boolean or text")
+
+	if def.Type == AttributeEnum {
+		v.Check(len(def.Options) > 0, "options", "must have at least one option")
+		v.Check(len(def.Options) <= 100, "options", "must not exceed 100 options")
+		v.Check(validator.Unique(def.Options), "options", "must not contain duplicate values")
+		for _, option := range def.Options {
+			v.Check(option != "" && len(option) <= 100, "options", "options must be between 1 and 100 characters")
+		}
+	} else {
+		v.Check(len(def.Options) == 0, "options", "are only allowed for enum attributes")
+	}
+}
+
+// validateProductAttributes checks a product's attribute values against the
+// definitions that apply to its categories. A key may have more than one definition
+// if the product is in unrelated categories that both define it, in which case the
+// value has to suit all of them.
+func validateProductAttributes(v *validator.Validator, attributes map[string]any, definitions []*AttributeDefinition) {
+	v.Check(len(attributes) <= 50, "attributes", "must not exceed 50 attributes")
+
+	byKey := make(map[string][]*AttributeDefinition)
+	for _, def := range definitions {
+		byKey[def.Key] = append(byKey[def.Key], def)
+	}
+
+	for key, value := range attributes {
+		field := "attributes." + key
+
+		defs, ok := byKey[key]
+		if !ok {
+			v.AddError(field, "is not defined for the product's categories")
+			continue
+		}
+
+		for _, def := range defs {
+			switch def.Type {
+			case AttributeEnum:
+				s, ok := value.(string)
+				v.Check(ok && slices.Contains(def.Options, s), field, "must be one of "+strings.Join(def.Options, ", "))
+			case AttributeNumber:
+				n, ok := value.(float64)
+				v.Check(ok && !math.IsNaN(n) && !math.IsInf(n, 0), field, "must be a number")
+			case AttributeBoolean:
+				_, ok := value.(bool)
+				v.Check(ok, field, "must be true or false")
+			case AttributeText:
+				s, ok := value.(string)
+				v.Check(ok && s != "", field, "must be a non-empty string")
+				v.Check(!ok || len(s) <= 500, field, "must not exceed 500 characters")
+			}
+		}
+	}
+}
+
+// ValidateAttributeFilters checks the attr.* filters of a product list.
+func ValidateAttributeFilters(v *validator.Validator, filters map[string][]string) {
+	v.Check(len(filters) <= MaxAttributeFilters, "attr", fmt.Sprintf("must not have more than %d attribute filters", MaxAttributeFilters))
+	for key, values := range filters {
+		field := "attr." + key
+		v.Check(validator.Matches(key, attributeKeyRX), field, "is not a valid attribute key")
+		v.Check(len(values) > 0, field, "must have at least one value")
+		v.Check(len(values) <= 20, field, "must not have more than 20 values")
+		for _, value := range values {
+			v.Check(value != "" && len(value) <= 100, field, "values must be between 1 and 100 characters")
+		}
+	}
+}
+
+// attributesPath turns attr.* filters into a jsonpath predicate for the @@ operator,
+// which the GIN index on products.attributes supports. A product must match every
+// filter, and any of each filter's values. A value that looks like a number or a
+// boolean also matches attributes of that type, so that the filters work without
+// knowing how each key is defined.
+func attributesPath(filters map[string][]string) string {
+	if len(filters) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	clauses := make([]string, 0, len(keys))
+	for _, key := range keys {
+		// Keys are validated against attributeKeyRX, so they need no escaping.
+		accessor := `$."` + key + `"`
+
+		alternatives := []string{}
+		for _, value := range filters[key] {
+			quoted, _ := json.Marshal(value)
+			alternatives = append(alternatives, accessor+" == "+string(quoted))
+
+			if n, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+				alternatives = append(alternatives, accessor+" == "+strconv.FormatFloat(n, 'f', -1, 64))
+			}
+			if b, err := strconv.ParseBool(value); err == nil {
+				alternatives = append(alternatives, accessor+" == "+strconv.FormatBool(b))
+			}
+		}
+		clauses = append(clauses, "("+strings.Join(alternatives, " || ")+")")
+	}
+
+	return strings.Join(clauses, " && ")
+}
+
+// marshalAttributes encodes a product's attributes for the attributes column, which
+// holds an empty object rather than null when there are none.
+func marshalAttributes(attributes map[string]any) ([]byte, error) {
+	if attributes == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(attributes)
+}
+
+func unmarshalAttributes(b []byte, attributes *map[string]any) error {
+	var decoded map[string]any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return fmt.Errorf("decode product attributes: %w", err)
+	}
+	if len(decoded) == 0 {
+		decoded = nil
+	}
+	*attributes = decoded
+	return nil
+}
+
+type AttributeModel struct {
+	DB *sql.DB
+}
+
+// attributeAncestors is a recursive CTE of the categories matching its condition and
+// all of their ancestors.
+const attributeAncestors = `
+        WITH RECURSIVE ancestors AS (
+            SELECT id, parent_id FROM categories WHERE %s
+            UNION
+            SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+        )`
+
+// Insert adds an attribute to a category. It returns ErrDuplicateAttribute if the key
+// is already defined on the category, its ancestors or its descendants.
+func (m AttributeModel) Insert(def *AttributeDefinition) error {
+	query := fmt.Sprintf(attributeAncestors, `id = $1`) + `,
+        descendants AS (
+            SELECT id FROM categories WHERE id = $1
+            UNION
+            SELECT c.id FROM categories c JOIN descendants d ON c.parent_id = d.id
+        )
+        INSERT INTO attribute_definitions (category_id, key, name, type, options)
+        SELECT $1, $2, $3, $4, $5
+        WHERE NOT EXISTS (
+            SELECT 1 FROM attribute_definitions
+            WHERE key = $2
+            AND (category_id IN (SELECT id FROM ancestors) OR category_id IN (SELECT id FROM descendants)))
+        RETURNING id, created_at, updated_at, version`
+
+	args := []interface{}{def.CategoryID, def.Key, def.Name, def.Type, pq.Array(def.Options)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&def.ID,
+		&def.CreatedAt,
+		&def.UpdatedAt,
+		&def.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows), isUniqueViolation(err):
+			return ErrDuplicateAttribute
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m AttributeModel) Get(id int64) (*AttributeDefinition, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT ` + attributeColumns + `
+        FROM attribute_definitions d
+        JOIN categories c ON c.id = d.category_id
+        WHERE d.id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	def, err := scanAttributeDefinition(m.DB.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return def, nil
+}
+
+// GetForCategory returns the attributes products in a category can have: its own and
+// those it inherits.
+func (m AttributeModel) GetForCategory(categoryID int64) ([]*AttributeDefinition, error) {
+	return m.list(fmt.Sprintf(attributeAncestors, `id = $1`), categoryID)
+}
+
+// GetForCategories returns the attributes a product in the given categories, by slug,
+// can have.
+func (m AttributeModel) GetForCategories(slugs []string) ([]*AttributeDefinition, error) {
+	if len(slugs) == 0 {
+		return []*AttributeDefinition{}, nil
+	}
+	return m.list(fmt.Sprintf(attributeAncestors, `slug = ANY($1)`), pq.Array(slugs))
+}
+
+func (m AttributeModel) list(ancestors string, arg interface{}) ([]*AttributeDefinition, error) {
+	query := ancestors + `
+        SELECT ` + attributeColumns + `
+        FROM attribute_definitions d
+        JOIN categories c ON c.id = d.category_id
+        WHERE d.category_id IN (SELECT id FROM ancestors)
+        ORDER BY d.key, d.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := []*AttributeDefinition{}
+	for rows.Next() {
+		def, err := scanAttributeDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
+
+// Update saves an attribute's name and options; its key and type can't be changed. It
+// returns ErrAttributeInUse if an option being removed is still used by a product.
+func (m AttributeModel) Update(def *AttributeDefinition) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if def.Type == AttributeEnum {
+		inUse, err := attributeInUseTx(ctx, tx, def, def.Options)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrAttributeInUse
+		}
+	}
+
+	query := `
+        UPDATE attribute_definitions
+        SET name = $1, options = $2, updated_at = NOW(), version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING version, updated_at`
+
+	args := []interface{}{def.Name, pq.Array(def.Options), def.ID, def.Version}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&def.Version, &def.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes an attribute. It returns ErrAttributeInUse if any product in the
+// category or its subcategories still has a value for it.
+func (m AttributeModel) Delete(def *AttributeDefinition) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	inUse, err := attributeInUseTx(ctx, tx, def, nil)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrAttributeInUse
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM attribute_definitions WHERE id = $1`, def.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// attributeInUseTx reports whether a product in the attribute's category, or its
+// subcategories, has a value for it other than one of allowed. A nil allowed counts
+// any value.
+func attributeInUseTx(ctx context.Context, tx *sql.Tx, def *AttributeDefinition, allowed []string) (bool, error) {
+	query := `
+        WITH RECURSIVE tree AS (
+            SELECT id, slug FROM categories WHERE id = $1
+            UNION
+            SELECT c.id, c.slug FROM categories c JOIN tree t ON c.parent_id = t.id
+        )
+        SELECT EXISTS (
+            SELECT 1 FROM products
+            WHERE category && ARRAY(SELECT slug FROM tree)
+            AND attributes ? $2
+            AND ($3::text[] IS NULL OR NOT (attributes->>$2 = ANY($3))))`
+
+	var allowedArg interface{}
+	if allowed != nil {
+		allowedArg = pq.Array(allowed)
+	}
+
+	var inUse bool
+	err := tx.QueryRowContext(ctx, query, def.CategoryID, def.Key, allowedArg).Scan(&inUse)
+	return inUse, err
+}
+
+const attributeColumns = `d.id, d.category_id, c.slug, d.key, d.name, d.type, d.options, d.created_at, d.updated_at, d.version`
+
+func scanAttributeDefinition(row rowScanner) (*AttributeDefinition, error) {
+	var def AttributeDefinition
+
+	err := row.Scan(
+		&def.ID,
+		&def.CategoryID,
+		&def.Category,
+		&def.Key,
+		&def.Name,
+		&def.Type,
+		pq.Array(&def.Options),
+		&def.CreatedAt,
+		&def.UpdatedAt,
+		&def.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(def.Options) == 0 {
+		def.Options = nil
+	}
+	return &def, nil
+}
@@ -0,0 +1,241 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrBrandInUse   = errors.New("brand in use")
+	ErrUnknownBrand = errors.New("unknown brand")
+)
+
+// Brand is a brand products can belong to. ProductCount is the number of its products
+// shoppers can currently see.
+type Brand struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Slug         string    `json:"slug"`
+	LogoURL      string    `json:"logo_url,omitempty"`
+	Description  string    `json:"description,omitempty"`
+	ProductCount int       `json:"product_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Version      int32     `json:"version"`
+}
+
+// ProductBrand is the brand as shown on a product.
+type ProductBrand struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func ValidateBrand(v *validator.Validator, brand *Brand) {
+	v.Check(strings.TrimSpace(brand.Name) != "", "name", "must be provided")
+	v.Check(len(brand.Name) <= 100, "name", "must not exceed 100 characters")
+
+	v.Check(brand.Slug != "", "slug", "must be provided")
+	v.Check(len(brand.Slug) <= 100, "slug", "must not exceed 100 characters")
+	v.Check(validator.Matches(brand.Slug, slugRX), "slug", "must contain only lowercase letters, digits and single hyphens")
+
+	if brand.LogoURL != "" {
+		v.Check(len(brand.LogoURL) <= 1000, "logo_url", "must not exceed 1000 characters")
+		v.Check(validator.IsURL(brand.LogoURL), "logo_url", "must be a valid URL")
+	}
+
+	v.Check(len(brand.Description) <= 5000, "description", "must not exceed 5000 characters")
+}
+
+type BrandModel struct {
+	DB *sql.DB
+}
+
+// brandColumns selects a brand and the number of its visible products.
+const brandColumns = `
+        b.id, b.name, b.slug, b.logo_url, b.description,
+        (SELECT count(*) FROM products WHERE products.brand_id = b.id AND ` + productVisible + `),
+        b.created_at, b.updated_at, b.version`
+
+// Insert adds a brand. It returns ErrDuplicateSlug if the slug is taken.
+func (m BrandModel) Insert(brand *Brand) error {
+	query := `
+        INSERT INTO brands (name, slug, logo_url, description)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []interface{}{brand.Name, brand.Slug, brand.LogoURL, brand.Description}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&brand.ID, &brand.CreatedAt, &brand.UpdatedAt, &brand.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateSlug
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBySlug fetches the brand with the given slug.
+func (m BrandModel) GetBySlug(slug string) (*Brand, error) {
+	query := `
+        SELECT ` + brandColumns + `
+        FROM brands b
+        WHERE b.slug = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	brand, err := scanBrand(m.DB.QueryRowContext(ctx, query, slug))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return brand, nil
+}
+
+// GetAll returns a page of brands, optionally only those whose name contains name.
+func (m BrandModel) GetAll(name string, filters Filters) ([]*Brand, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT count(*) OVER(), `+brandColumns+`
+        FROM brands b
+        WHERE ($1 = '' OR b.name ILIKE '%%' || $1 || '%%')
+        ORDER BY b.%s %s, b.id ASC
+        LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	brands := []*Brand{}
+
+	for rows.Next() {
+		var brand Brand
+		err := rows.Scan(
+			&totalRecords,
+			&brand.ID,
+			&brand.Name,
+			&brand.Slug,
+			&brand.LogoURL,
+			&brand.Description,
+			&brand.ProductCount,
+			&brand.CreatedAt,
+			&brand.UpdatedAt,
+			&brand.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		brands = append(brands, &brand)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return brands, metadata, nil
+}
+
+// Update saves a brand. It returns ErrDuplicateSlug if the new slug is taken.
+func (m BrandModel) Update(brand *Brand) error {
+	query := `
+        UPDATE brands
+        SET name = $1, slug = $2, logo_url = $3, description = $4, updated_at = NOW(), version = version + 1
+        WHERE id = $5 AND version = $6
+        RETURNING updated_at, version`
+
+	args := []interface{}{brand.Name, brand.Slug, brand.LogoURL, brand.Description, brand.ID, brand.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&brand.UpdatedAt, &brand.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isUniqueViolation(err):
+			return ErrDuplicateSlug
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a brand. Brands that products still belong to, in any status, return
+// ErrBrandInUse.
+func (m BrandModel) Delete(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `DELETE FROM brands WHERE id = $1`, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrBrandInUse
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func scanBrand(row rowScanner) (*Brand, error) {
+	var brand Brand
+
+	err := row.Scan(
+		&brand.ID,
+		&brand.Name,
+		&brand.Slug,
+		&brand.LogoURL,
+		&brand.Description,
+		&brand.ProductCount,
+		&brand.CreatedAt,
+		&brand.UpdatedAt,
+		&brand.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &brand, nil
+}
+
+// isUnknownBrand reports whether saving a product failed because its brand doesn't
+// exist.
+func isUnknownBrand(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "products_brand_id_fkey"
+}
@@ -0,0 +1,247 @@
+package data
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/lru"
+)
+
+// ProductChangesChannel is the Postgres channel a notification is sent on whenever a
+// product changes, by a trigger on products. The payload is the product's ID, or empty
+// if only product lists are affected.
+const ProductChangesChannel = "product_changes"
+
+// CatalogueCache keeps recently read products, and pages of the public product list,
+// in memory. Products are cached by ID and lists by their normalised search and
+// filters. A change to a product drops it and every cached list, since it could have
+// moved in or out of any of them.
+//
+// ProductModel drops what it changes itself straight away. Everything else, including
+// changes made by other instances, is dropped when the notification arrives on
+// ProductChangesChannel. Entries also expire after a TTL, because lists and prices
+// depend on the time as well as on the data.
+//
+// A nil *CatalogueCache caches nothing.
+type CatalogueCache struct {
+	products *lru.Cache[int64, *Product]
+	lists    *lru.Cache[string, cachedList]
+}
+
+type cachedList struct {
+	products []*Product
+	metadata Metadata
+}
+
+// NewCatalogueCache returns a cache holding up to productEntries products and
+// listEntries product list pages, each for up to ttl.
+func NewCatalogueCache(productEntries, listEntries int, ttl time.Duration) *CatalogueCache {
+	return &CatalogueCache{
+		products: lru.New[int64, *Product](productEntries, ttl),
+		lists:    lru.New[string, cachedList](listEntries, ttl),
+	}
+}
+
+// Invalidate drops a product, and every list, from the cache.
+func (c *CatalogueCache) Invalidate(id int64) {
+	if c == nil {
+		return
+	}
+	c.products.Remove(id)
+	c.lists.Purge()
+}
+
+// InvalidateLists drops every list from the cache.
+func (c *CatalogueCache) InvalidateLists() {
+	if c == nil {
+		return
+	}
+	c.lists.Purge()
+}
+
+// InvalidateAll empties the cache, for when changes may have been missed.
+func (c *CatalogueCache) InvalidateAll() {
+	if c == nil {
+		return
+	}
+	c.products.Purge()
+	c.lists.Purge()
+}
+
+// Stats returns the counters of the product and list caches, for /debug/vars.
+func (c *CatalogueCache) Stats() map[string]lru.Stats {
+	if c == nil {
+		return nil
+	}
+	return map[string]lru.Stats{
+		"products": c.products.Stats(),
+		"lists":    c.lists.Stats(),
+	}
+}
+
+// The getters return copies, with the prices worked out again for the current time,
+// since callers are free to change what they are given.
+
+func (c *CatalogueCache) getProduct(id int64, now time.Time) (*Product, bool) {
+	if c == nil {
+		return nil, false
+	}
+	product, ok := c.products.Get(id)
+	if !ok {
+		return nil, false
+	}
+	return product.cachedCopy(now), true
+}
+
+func (c *CatalogueCache) productGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.products.Generation()
+}
+
+func (c *CatalogueCache) addProduct(product *Product, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.products.Add(product.ID, product.cachedCopy(time.Time{}), generation)
+}
+
+func (c *CatalogueCache) getList(key string, now time.Time) ([]*Product, Metadata, bool) {
+	if c == nil || key == "" {
+		return nil, Metadata{}, false
+	}
+	list, ok := c.lists.Get(key)
+	if !ok {
+		return nil, Metadata{}, false
+	}
+	products := make([]*Product, len(list.products))
+	for i, product := range list.products {
+		products[i] = product.cachedCopy(now)
+	}
+	return products, list.metadata, true
+}
+
+func (c *CatalogueCache) listGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.lists.Generation()
+}
+
+func (c *CatalogueCache) addList(key string, products []*Product, metadata Metadata, generation uint64) {
+	if c == nil || key == "" {
+		return
+	}
+	list := cachedList{products: make([]*Product, len(products)), metadata: metadata}
+	for i, product := range products {
+		list.products[i] = product.cachedCopy(time.Time{})
+	}
+	c.lists.Add(key, list, generation)
+}
+
+// cachedCopy returns a copy of p that shares nothing with it. If now isn't zero, the
+// copy's prices are worked out for that time.
+func (p *Product) cachedCopy(now time.Time) *Product {
+	product := *p
+	product.Category = slices.Clone(p.Category)
+	product.PublishAt = clonePointer(p.PublishAt)
+	product.UnpublishAt = clonePointer(p.UnpublishAt)
+	product.CompareAtPrice = clonePointer(p.CompareAtPrice)
+	product.SalePrice = clonePointer(p.SalePrice)
+	product.SaleStartsAt = clonePointer(p.SaleStartsAt)
+	product.SaleEndsAt = clonePointer(p.SaleEndsAt)
+	product.WasPrice = clonePointer(p.WasPrice)
+	product.LowStockThreshold = clonePointer(p.LowStockThreshold)
+	product.Attributes = maps.Clone(p.Attributes)
+	product.BrandID = clonePointer(p.BrandID)
+	product.Brand = clonePointer(p.Brand)
+	product.Variants = nil
+	product.Images = nil
+
+	if !now.IsZero() {
+		product.setPricing(now)
+	}
+	return &product
+}
+
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// listCacheKey returns the key a page of the product list is cached under, or "" if
+// it shouldn't be cached. Only the public catalogue is cached, and not free-text
+// searches: browsing by category, seller, price and so on is where the same queries
+// come up again and again, and the long tail of searches would only push them out.
+func listCacheKey(search ProductSearch, filters Filters) string {
+	if search.OwnerID != 0 || search.ReviewQueue || search.Query != "" || search.Name != "" {
+		return ""
+	}
+
+	// The same list can be asked for in more than one way, so values whose order or
+	// case doesn't matter to the query are put in a standard form.
+	normalise := func(values []string, lower bool) []string {
+		out := make([]string, 0, len(values))
+		for _, value := range values {
+			value = strings.TrimSpace(value)
+			if lower {
+				value = strings.ToLower(value)
+			}
+			out = append(out, value)
+		}
+		slices.Sort(out)
+		return slices.Compact(out)
+	}
+
+	normalisedAttributes := make(map[string][]string, len(search.Attributes))
+	for key, values := range search.Attributes {
+		normalisedAttributes[key] = normalise(values, false)
+	}
+
+	key, err := json.Marshal(struct {
+		Category  []string `json:"c,omitempty"`
+		Sizes     []string `json:"s,omitempty"`
+		Colours   []string `json:"co,omitempty"`
+		MinPrice  *float64 `json:"min,omitempty"`
+		MaxPrice  *float64 `json:"max,omitempty"`
+		InStock   bool     `json:"in,omitempty"`
+		Status    string   `json:"st,omitempty"`
+		SellerID  int64    `json:"se,omitempty"`
+		OnSale    bool     `json:"os,omitempty"`
+		Attrs     string   `json:"a,omitempty"`
+		Brands    []string `json:"b,omitempty"`
+		Page      int      `json:"p"`
+		PageSize  int      `json:"ps"`
+		Sort      string   `json:"so"`
+		Cursor    string   `json:"cu,omitempty"`
+		WithTotal bool     `json:"t,omitempty"`
+	}{
+		Category:  normalise(search.Category, false),
+		Sizes:     normalise(search.Sizes, true),
+		Colours:   normalise(search.Colours, true),
+		MinPrice:  search.MinPrice,
+		MaxPrice:  search.MaxPrice,
+		InStock:   search.InStock,
+		Status:    search.Status,
+		SellerID:  search.SellerID,
+		OnSale:    search.OnSale,
+		Attrs:     attributesPath(normalisedAttributes),
+		Brands:    normalise(search.Brands, false),
+		Page:      filters.Page,
+		PageSize:  filters.PageSize,
+		Sort:      filters.Sort,
+		Cursor:    filters.Cursor,
+		WithTotal: filters.WithTotal,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(key)
+}
@@ -0,0 +1,346 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateSlug = errors.New("duplicate slug")
+	ErrCategoryCycle = errors.New("category cycle")
+	ErrCategoryInUse = errors.New("category in use")
+	ErrUnknownParent = errors.New("unknown parent category")
+	slugRX           = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	nonSlugCharsRX   = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Category is a node in the product taxonomy. Products refer to categories by slug.
+type Category struct {
+	ID        int64       `json:"id"`
+	ParentID  *int64      `json:"parent_id"`
+	Name      string      `json:"name"`
+	Slug      string      `json:"slug"`
+	SortOrder int32       `json:"sort_order"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Version   int32       `json:"version"`
+	Children  []*Category `json:"children,omitempty"`
+}
+
+// Slugify turns a category name such as "Men's Shoes" into a slug ("men-s-shoes").
+func Slugify(s string) string {
+	return strings.Trim(nonSlugCharsRX.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(strings.TrimSpace(category.Name) != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 100, "name", "must not exceed 100 characters")
+
+	v.Check(category.Slug != "", "slug", "must be provided")
+	v.Check(len(category.Slug) <= 100, "slug", "must not exceed 100 characters")
+	v.Check(validator.Matches(category.Slug, slugRX), "slug", "must contain only lowercase letters, digits and single hyphens")
+
+	if category.ParentID != nil {
+		v.Check(*category.ParentID > 0, "parent_id", "must be a positive integer")
+		v.Check(*category.ParentID != category.ID, "parent_id", "must not be the category itself")
+	}
+}
+
+// BuildCategoryTree nests a flat list of categories under their parents. The order of
+// the list is kept among siblings.
+func BuildCategoryTree(categories []*Category) []*Category {
+	byID := make(map[int64]*Category, len(categories))
+	for _, c := range categories {
+		c.Children = nil
+		byID[c.ID] = c
+	}
+
+	roots := []*Category{}
+	for _, c := range categories {
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, c)
+				continue
+			}
+		}
+		roots = append(roots, c)
+	}
+	return roots
+}
+
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+func (m CategoryModel) Insert(category *Category) error {
+	query := `
+        INSERT INTO categories (parent_id, name, slug, sort_order)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []interface{}{category.ParentID, category.Name, category.Slug, category.SortOrder}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&category.ID,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+		&category.Version,
+	)
+	if err != nil {
+		return categoryWriteError(err)
+	}
+	return nil
+}
+
+func (m CategoryModel) Get(id int64) (*Category, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, parent_id, name, slug, sort_order, created_at, updated_at, version
+        FROM categories
+        WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var category Category
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&category.ID,
+		&category.ParentID,
+		&category.Name,
+		&category.Slug,
+		&category.SortOrder,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+		&category.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &category, nil
+}
+
+// GetAll returns every category as a flat list in display order.
+func (m CategoryModel) GetAll() ([]*Category, error) {
+	query := `
+        SELECT id, parent_id, name, slug, sort_order, created_at, updated_at, version
+        FROM categories
+        ORDER BY sort_order, name, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID,
+			&category.ParentID,
+			&category.Name,
+			&category.Slug,
+			&category.SortOrder,
+			&category.CreatedAt,
+			&category.UpdatedAt,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// Update saves a category. It returns ErrCategoryCycle if the new parent is the
+// category itself or one of its descendants. If the slug changes, products are moved
+// over to the new slug in the same transaction.
+func (m CategoryModel) Update(category *Category, oldSlug string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if category.ParentID != nil {
+		var cycle bool
+		err = tx.QueryRowContext(ctx, `
+            WITH RECURSIVE descendants AS (
+                SELECT id FROM categories WHERE id = $1
+                UNION
+                SELECT c.id FROM categories c JOIN descendants d ON c.parent_id = d.id
+            )
+            SELECT EXISTS (SELECT 1 FROM descendants WHERE id = $2)`,
+			category.ID, *category.ParentID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	query := `
+        UPDATE categories
+        SET parent_id = $1, name = $2, slug = $3, sort_order = $4, updated_at = NOW(), version = version + 1
+        WHERE id = $5 AND version = $6
+        RETURNING version, updated_at`
+
+	args := []interface{}{
+		category.ParentID,
+		category.Name,
+		category.Slug,
+		category.SortOrder,
+		category.ID,
+		category.Version,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&category.Version, &category.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return categoryWriteError(err)
+		}
+	}
+
+	if category.Slug != oldSlug {
+		_, err = tx.ExecContext(ctx, `
+            UPDATE products SET category = array_replace(category, $1, $2), updated_at = NOW()
+            WHERE category @> ARRAY[$1]::text[]`, oldSlug, category.Slug)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a category. Categories that still have children or products return
+// ErrCategoryInUse.
+func (m CategoryModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM categories c
+        WHERE c.id = $1
+        AND NOT EXISTS (SELECT 1 FROM categories child WHERE child.parent_id = c.id)
+        AND NOT EXISTS (SELECT 1 FROM products p WHERE p.category @> ARRAY[c.slug]::text[])`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		// Work out whether it didn't exist or is still in use.
+		if _, err := m.Get(id); err != nil {
+			return err
+		}
+		return ErrCategoryInUse
+	}
+
+	return nil
+}
+
+// ResolveSlugs maps category names or slugs, matched case-insensitively, to the slugs
+// in the taxonomy. Values that don't match any category are returned in unknown.
+func (m CategoryModel) ResolveSlugs(values []string) (slugs []string, unknown []string, err error) {
+	wanted := make([]string, len(values))
+	for i, value := range values {
+		wanted[i] = Slugify(value)
+	}
+
+	query := `
+        SELECT slug FROM categories
+        WHERE slug = ANY($1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(wanted))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, nil, err
+		}
+		known[slug] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	slugs = []string{}
+	for i, slug := range wanted {
+		if !known[slug] {
+			unknown = append(unknown, values[i])
+			continue
+		}
+		slugs = append(slugs, slug)
+	}
+
+	return slugs, unknown, nil
+}
+
+func categoryWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrDuplicateSlug
+		case "23503":
+			return ErrUnknownParent
+		}
+	}
+	return err
+}
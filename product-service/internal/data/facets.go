@@ -0,0 +1,213 @@
+package data
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+// FacetNames are the facets the product list can return.
+var FacetNames = []string{"category", "price", "in_stock"}
+
+// maxCategoryBuckets limits the category facet to the most common categories.
+const maxCategoryBuckets = 50
+
+// FacetBucket is the number of matching products with a particular value.
+type FacetBucket struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket is the number of matching products priced from Min up to, but not
+// including, Max. The last bucket has no Max.
+type PriceBucket struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// Facets holds the requested facets for a product search. Facets that weren't asked
+// for are left nil.
+type Facets struct {
+	Category []FacetBucket `json:"category,omitempty"`
+	Price    []PriceBucket `json:"price,omitempty"`
+	InStock  []FacetBucket `json:"in_stock,omitempty"`
+}
+
+func ValidateFacets(v *validator.Validator, names []string, priceEdges []float64) {
+	for _, name := range names {
+		v.Check(validator.In(name, FacetNames...), "facets", "must only contain "+strings.Join(FacetNames, ", "))
+	}
+	v.Check(validator.Unique(names), "facets", "must not contain duplicate values")
+
+	v.Check(len(priceEdges) >= 1, "price_buckets", "must contain at least one value")
+	v.Check(len(priceEdges) <= 20, "price_buckets", "must not contain more than 20 values")
+	v.Check(sort.Float64sAreSorted(priceEdges), "price_buckets", "must be in ascending order")
+	for i := range priceEdges {
+		v.Check(priceEdges[i] >= 0, "price_buckets", "must not contain negative values")
+		if i > 0 {
+			v.Check(priceEdges[i] != priceEdges[i-1], "price_buckets", "must not contain duplicate values")
+		}
+	}
+}
+
+// GetFacets counts the products matching search by each of the named facets. All of
+// the facets are computed by a single query which scans the matching products once.
+// priceEdges are the lower bounds of the price buckets.
+func (m ProductModel) GetFacets(search ProductSearch, names []string, priceEdges []float64) (*Facets, error) {
+	facets := &Facets{}
+	if len(names) == 0 {
+		return facets, nil
+	}
+
+	parts := []string{}
+	for _, name := range names {
+		switch name {
+		case "category":
+			parts = append(parts, `
+            (SELECT 'category', c, count(*)
+             FROM matches, unnest(category) AS c
+             GROUP BY c
+             ORDER BY count(*) DESC, c
+             LIMIT `+strconv.Itoa(maxCategoryBuckets)+`)`)
+		case "price":
+			parts = append(parts, `
+            (SELECT 'price', width_bucket(price, $17::numeric[])::text, count(*)
+             FROM matches
+             GROUP BY 2)`)
+		case "in_stock":
+			parts = append(parts, `
+            (SELECT 'in_stock', in_stock::text, count(*)
+             FROM matches
+             GROUP BY 2)`)
+		}
+	}
+
+	query := `
+        WITH matches AS MATERIALIZED (
+            SELECT id, ` + productEffectivePrice + ` AS price, category,
+                   stock > 0 OR EXISTS (
+                       SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.stock > 0
+                   ) AS in_stock
+            FROM products
+            WHERE ` + productSearchConditions + `
+        )` + strings.Join(parts, "\n        UNION ALL")
+
+	// $17 is only referenced, and so only passed, when the price facet is requested.
+	args := search.args()
+	for _, name := range names {
+		if name == "price" {
+			args = append(args, pq.Array(priceEdges))
+			break
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	priceCounts := make(map[string]int)
+	inStockCounts := map[string]int{"true": 0, "false": 0}
+	wantPrice, wantInStock := false, false
+	for _, name := range names {
+		switch name {
+		case "price":
+			wantPrice = true
+		case "in_stock":
+			wantInStock = true
+		case "category":
+			facets.Category = []FacetBucket{}
+		}
+	}
+
+	for rows.Next() {
+		var facet, value string
+		var count int
+		if err := rows.Scan(&facet, &value, &count); err != nil {
+			return nil, err
+		}
+
+		switch facet {
+		case "category":
+			facets.Category = append(facets.Category, FacetBucket{Value: value, Count: count})
+		case "price":
+			priceCounts[value] = count
+		case "in_stock":
+			inStockCounts[value] = count
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(facets.Category) > 0 {
+		if err := m.nameCategoryBuckets(ctx, facets.Category); err != nil {
+			return nil, err
+		}
+	}
+
+	if wantPrice {
+		// width_bucket numbers the buckets from 1; prices below the first edge fall in
+		// bucket 0 and aren't reported.
+		facets.Price = make([]PriceBucket, len(priceEdges))
+		for i := range priceEdges {
+			facets.Price[i] = PriceBucket{Min: priceEdges[i], Count: priceCounts[strconv.Itoa(i+1)]}
+			if i+1 < len(priceEdges) {
+				upper := priceEdges[i+1]
+				facets.Price[i].Max = &upper
+			}
+		}
+	}
+
+	if wantInStock {
+		facets.InStock = []FacetBucket{
+			{Value: "true", Count: inStockCounts["true"]},
+			{Value: "false", Count: inStockCounts["false"]},
+		}
+	}
+
+	return facets, nil
+}
+
+// nameCategoryBuckets fills in the display name of each category bucket.
+func (m ProductModel) nameCategoryBuckets(ctx context.Context, buckets []FacetBucket) error {
+	slugs := make([]string, len(buckets))
+	for i := range buckets {
+		slugs[i] = buckets[i].Value
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `SELECT slug, name FROM categories WHERE slug = ANY($1)`, pq.Array(slugs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	for rows.Next() {
+		var slug, name string
+		if err := rows.Scan(&slug, &name); err != nil {
+			return err
+		}
+		names[slug] = name
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range buckets {
+		buckets[i].Name = names[buckets[i].Value]
+	}
+	return nil
+}
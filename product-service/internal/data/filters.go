@@ -1,8 +1,14 @@
 package data
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"math"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
 )
@@ -12,6 +18,112 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	// Cursor is the next_cursor of a previous page, which the list carries on from in
+	// place of Page. Lists that support it read the records after the cursor's rather
+	// than skipping Page-1 pages, which stays fast however deep it goes and doesn't
+	// skip or repeat records when others are added or removed.
+	Cursor string
+	// WithTotal asks for the total number of records, which means counting every
+	// match. Lists without cursors always count them.
+	WithTotal bool
+}
+
+// cursor is what a Filters.Cursor holds: the sort it was issued for, and the sort
+// value and ID of the last record on the page, as text.
+type cursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   int64  `json:"i"`
+}
+
+func encodeCursor(sort, key string, id int64) string {
+	js, _ := json.Marshal(cursor{Sort: sort, Key: key, ID: id})
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func (f Filters) decodeCursor() (*cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(js, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// numericKey matches a number as Postgres writes out a numeric, and realKey one as it
+// writes out a real, which may have an exponent.
+var (
+	numericKey = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	realKey    = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?(e[-+][0-9]+)?$`)
+)
+
+// validCursorKey reports whether key, the sort value held by a cursor, can be compared
+// with the sort column, so that a cursor that has been tampered with is rejected as
+// invalid rather than failing in the database.
+func validCursorKey(column, key string) bool {
+	switch column {
+	case "id":
+		_, err := strconv.ParseInt(key, 10, 64)
+		return err == nil
+	case "created_at":
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999Z07", "2006-01-02 15:04:05.999999999Z07:00"} {
+			if _, err := time.Parse(layout, key); err == nil {
+				return true
+			}
+		}
+		return false
+	case "name":
+		return !strings.ContainsRune(key, 0)
+	case "relevance":
+		// A real: Postgres rejects anything beyond a float32's range, or too small to be
+		// told apart from zero.
+		f, err := strconv.ParseFloat(key, 32)
+		mantissa, _, _ := strings.Cut(key, "e")
+		return realKey.MatchString(key) && err == nil && (f != 0 || !strings.ContainsAny(mantissa, "123456789"))
+	default:
+		return numericKey.MatchString(key)
+	}
+}
+
+// keyset returns the condition that selects the records after the cursor, in the
+// order given by sortColumn and sortDirection and then by ID, using parameters $n and
+// $n+1, along with their values. It returns TRUE and no values if there's no cursor.
+// The cursor must already have been validated by ValidateFilters.
+func (f Filters) keyset(column string, n int) (string, []interface{}) {
+	if f.Cursor == "" {
+		return "TRUE", nil
+	}
+	c, _ := f.decodeCursor()
+
+	op := ">"
+	if f.sortDirection() == "DESC" {
+		op = "<"
+	}
+	condition := fmt.Sprintf("(%[1]s %[2]s $%[3]d OR (%[1]s = $%[3]d AND id > $%[4]d))", column, op, n, n+1)
+	return condition, []interface{}{c.Key, c.ID}
+}
+
+// metadata describes a page of a list that supports cursors. nextCursor is empty on
+// the last page. Without WithTotal, LastPage and TotalRecords are left out; with a
+// cursor, so are the page numbers.
+func (f Filters) metadata(totalRecords int, nextCursor string) Metadata {
+	var metadata Metadata
+	switch {
+	case f.Cursor == "" && f.WithTotal:
+		metadata = calculateMetadata(totalRecords, f.Page, f.PageSize)
+	case f.Cursor == "":
+		metadata = Metadata{CurrentPage: f.Page, PageSize: f.PageSize, FirstPage: 1}
+	default:
+		metadata = Metadata{PageSize: f.PageSize}
+		if f.WithTotal {
+			metadata.TotalRecords = totalRecords
+		}
+	}
+	metadata.NextCursor = nextCursor
+	return metadata
 }
 
 func (f Filters) limit() int {
@@ -19,14 +131,26 @@ func (f Filters) limit() int {
 }
 
 func (f Filters) offset() int {
+	if f.Cursor != "" {
+		return 0
+	}
 	return (f.Page - 1) * f.PageSize
 }
 
 var sortMap = map[string]string{
 	"id": "id", "-id": "id",
 	"name": "name", "-name": "name",
-	"price": "price", "-price": "price",
+	"price": "effective_price", "-price": "effective_price",
 	"created_at": "created_at", "-created_at": "created_at",
+	"relevance": "relevance",
+	"rating":    "rating_average", "-rating": "rating_average",
+	"discount": "discount_percent", "-discount": "discount_percent",
+}
+
+// reviewSortMap maps the sort values of the review list to their columns.
+var reviewSortMap = map[string]string{
+	"created_at": "created_at", "-created_at": "created_at",
+	"rating": "rating", "-rating": "rating",
 }
 
 func (f Filters) sortColumn() string {
@@ -37,9 +161,21 @@ func (f Filters) sortColumn() string {
 	return col
 }
 
+func (f Filters) reviewSortColumn() string {
+	col, ok := reviewSortMap[f.Sort]
+	if !ok {
+		return "created_at"
+	}
+	return col
+}
+
 // Return the sort direction ("ASC" or "DESC") depending on the prefix character of the
 // Sort field.
 func (f Filters) sortDirection() string {
+	// The best matches come first.
+	if f.Sort == "relevance" {
+		return "DESC"
+	}
 	if strings.HasPrefix(f.Sort, "-") {
 		return "DESC"
 	}
@@ -54,6 +190,16 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 	// Check that the sort parameter matches a value in the safelist.
 	v.Check(validator.In(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+
+	if f.Cursor != "" {
+		c, err := f.decodeCursor()
+		if err != nil {
+			v.AddError("cursor", "is invalid")
+		} else {
+			v.Check(c.Sort == f.Sort, "cursor", "was issued for a different sort")
+			v.Check(validCursorKey(f.sortColumn(), c.Key) && c.ID > 0, "cursor", "is invalid")
+		}
+	}
 }
 
 type Metadata struct {
@@ -62,6 +208,8 @@ type Metadata struct {
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
+	// NextCursor fetches the following page; it is left out on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -0,0 +1,342 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrTooManyImages = errors.New("too many images")
+
+// MaxImagesPerProduct is the size of a product's gallery.
+const MaxImagesPerProduct = 10
+
+// ProductImage is an uploaded product photo together with its smaller renditions.
+// The primary image is the one shown in listings; the rest of the gallery is shown
+// in position order.
+type ProductImage struct {
+	ID           int64     `json:"id"`
+	ProductID    int64     `json:"product_id"`
+	Position     int32     `json:"position"`
+	IsPrimary    bool      `json:"is_primary"`
+	URL          string    `json:"url"`
+	MediumURL    string    `json:"medium_url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	StorageKeys  []string  `json:"-"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ImageModel struct {
+	DB *sql.DB
+}
+
+// Insert adds an image to the end of the product's gallery. The first image a product
+// gets is always primary. It returns ErrTooManyImages if the gallery is full.
+func (m ImageModel) Insert(image *ProductImage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the product so concurrent uploads agree on positions and the primary image.
+	_, err = tx.ExecContext(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, image.ProductID)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	var hasPrimary bool
+	var nextPosition int32
+
+	err = tx.QueryRowContext(ctx, `
+        SELECT count(*), coalesce(bool_or(is_primary), false), coalesce(max(position), 0) + 1
+        FROM product_images
+        WHERE product_id = $1`, image.ProductID).Scan(&count, &hasPrimary, &nextPosition)
+	if err != nil {
+		return err
+	}
+
+	if count >= MaxImagesPerProduct {
+		return ErrTooManyImages
+	}
+
+	image.Position = nextPosition
+	if !hasPrimary {
+		image.IsPrimary = true
+	}
+
+	if image.IsPrimary && hasPrimary {
+		if err := clearPrimaryTx(ctx, tx, image.ProductID); err != nil {
+			return err
+		}
+	}
+
+	query := `
+        INSERT INTO product_images (product_id, position, is_primary, url, medium_url, thumbnail_url,
+            storage_keys, content_type, width, height, size_bytes)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING id, created_at`
+
+	args := []interface{}{
+		image.ProductID,
+		image.Position,
+		image.IsPrimary,
+		image.URL,
+		image.MediumURL,
+		image.ThumbnailURL,
+		pq.Array(image.StorageKeys),
+		image.ContentType,
+		image.Width,
+		image.Height,
+		image.SizeBytes,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&image.ID, &image.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if image.IsPrimary {
+		if err := setProductImageURLTx(ctx, tx, image.ProductID, image.URL); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get fetches an image, making sure it belongs to the given product.
+func (m ImageModel) Get(id, productID int64) (*ProductImage, error) {
+	if id < 1 || productID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, product_id, position, is_primary, url, medium_url, thumbnail_url, storage_keys,
+               content_type, width, height, size_bytes, created_at
+        FROM product_images
+        WHERE id = $1 AND product_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	image, err := scanImage(m.DB.QueryRowContext(ctx, query, id, productID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return image, nil
+}
+
+// GetAllForProduct returns a product's gallery, primary image first.
+func (m ImageModel) GetAllForProduct(productID int64) ([]*ProductImage, error) {
+	query := `
+        SELECT id, product_id, position, is_primary, url, medium_url, thumbnail_url, storage_keys,
+               content_type, width, height, size_bytes, created_at
+        FROM product_images
+        WHERE product_id = $1
+        ORDER BY is_primary DESC, position, id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []*ProductImage{}
+
+	for rows.Next() {
+		image, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// Update saves an image's position and primary flag. Making an image primary demotes
+// the previous primary image and points the product's image_url at it.
+func (m ImageModel) Update(image *ProductImage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, image.ProductID)
+	if err != nil {
+		return err
+	}
+
+	var wasPrimary bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT is_primary FROM product_images WHERE id = $1 AND product_id = $2`,
+		image.ID, image.ProductID).Scan(&wasPrimary)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	// The primary image can only be replaced, not unset, so a gallery always has one.
+	if wasPrimary {
+		image.IsPrimary = true
+	}
+
+	if image.IsPrimary && !wasPrimary {
+		if err := clearPrimaryTx(ctx, tx, image.ProductID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE product_images SET position = $1, is_primary = $2 WHERE id = $3`,
+		image.Position, image.IsPrimary, image.ID)
+	if err != nil {
+		return err
+	}
+
+	if image.IsPrimary && !wasPrimary {
+		if err := setProductImageURLTx(ctx, tx, image.ProductID, image.URL); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes an image from the gallery and returns it so the caller can remove
+// the stored files. If it was the primary image, the next image in the gallery is
+// promoted.
+func (m ImageModel) Delete(id, productID int64) (*ProductImage, error) {
+	if id < 1 || productID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT id FROM products WHERE id = $1 FOR UPDATE`, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+        DELETE FROM product_images
+        WHERE id = $1 AND product_id = $2
+        RETURNING id, product_id, position, is_primary, url, medium_url, thumbnail_url, storage_keys,
+                  content_type, width, height, size_bytes, created_at`
+
+	image, err := scanImage(tx.QueryRowContext(ctx, query, id, productID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if image.IsPrimary {
+		var nextURL string
+		err = tx.QueryRowContext(ctx, `
+            UPDATE product_images SET is_primary = TRUE
+            WHERE id = (
+                SELECT id FROM product_images WHERE product_id = $1 ORDER BY position, id LIMIT 1)
+            RETURNING url`, productID).Scan(&nextURL)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// That was the last image; leave the product's image_url alone.
+		case err != nil:
+			return nil, err
+		default:
+			if err := setProductImageURLTx(ctx, tx, productID, nextURL); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func clearPrimaryTx(ctx context.Context, tx *sql.Tx, productID int64) error {
+	_, err := tx.ExecContext(ctx, `
+        UPDATE product_images SET is_primary = FALSE
+        WHERE product_id = $1 AND is_primary`, productID)
+	return err
+}
+
+// setProductImageURLTx keeps products.image_url pointing at the primary image, so
+// listings and other services keep working with a single URL.
+func setProductImageURLTx(ctx context.Context, tx *sql.Tx, productID int64, url string) error {
+	_, err := tx.ExecContext(ctx, `
+        UPDATE products SET image_url = $1, updated_at = NOW(), version = version + 1
+        WHERE id = $2`, url, productID)
+	return err
+}
+
+func scanImage(row rowScanner) (*ProductImage, error) {
+	var image ProductImage
+
+	err := row.Scan(
+		&image.ID,
+		&image.ProductID,
+		&image.Position,
+		&image.IsPrimary,
+		&image.URL,
+		&image.MediumURL,
+		&image.ThumbnailURL,
+		pq.Array(&image.StorageKeys),
+		&image.ContentType,
+		&image.Width,
+		&image.Height,
+		&image.SizeBytes,
+		&image.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
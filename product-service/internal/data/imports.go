@@ -0,0 +1,223 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Import job statuses.
+const (
+	ImportQueued    = "queued"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportFormats are the file formats products can be imported from and exported to.
+var ImportFormats = []string{"csv", "ndjson"}
+
+// ImportJob is a bulk product import. Rows are matched to the seller's existing
+// products by SKU: matches are updated and the rest created. Rows that fail
+// validation are skipped and reported in RowErrors; Error is only set if the job
+// couldn't run at all.
+type ImportJob struct {
+	ID            int64            `json:"id"`
+	UserID        int64            `json:"user_id"`
+	Format        string           `json:"format"`
+	Status        string           `json:"status"`
+	TotalRows     int              `json:"total_rows"`
+	ProcessedRows int              `json:"processed_rows"`
+	CreatedRows   int              `json:"created_rows"`
+	UpdatedRows   int              `json:"updated_rows"`
+	FailedRows    int              `json:"failed_rows"`
+	RowErrors     []ImportRowError `json:"row_errors"`
+	Error         string           `json:"error,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	StartedAt     *time.Time       `json:"started_at,omitempty"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty"`
+}
+
+// ImportRowError lists what was wrong with one row of an import. Row counts from 1,
+// not including a CSV header.
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	SKU    string            `json:"sku,omitempty"`
+	Errors map[string]string `json:"errors"`
+}
+
+type ImportModel struct {
+	DB *sql.DB
+}
+
+// Insert queues a job along with the file to be imported.
+func (m ImportModel) Insert(job *ImportJob, payload []byte) error {
+	query := `
+        INSERT INTO import_jobs (user_id, format, total_rows, payload)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, status, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job.RowErrors = []ImportRowError{}
+
+	return m.DB.QueryRowContext(ctx, query, job.UserID, job.Format, job.TotalRows, payload).
+		Scan(&job.ID, &job.Status, &job.CreatedAt)
+}
+
+// Get fetches one of a user's jobs, without its payload.
+func (m ImportModel) Get(id, userID int64) (*ImportJob, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, user_id, format, status, total_rows, processed_rows, created_rows, updated_rows, failed_rows,
+               row_errors, error, created_at, started_at, finished_at
+        FROM import_jobs
+        WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var job ImportJob
+	var rowErrors []byte
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Format,
+		&job.Status,
+		&job.TotalRows,
+		&job.ProcessedRows,
+		&job.CreatedRows,
+		&job.UpdatedRows,
+		&job.FailedRows,
+		&rowErrors,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(rowErrors, &job.RowErrors); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// GetUnfinished returns the IDs of jobs that are queued or were interrupted while
+// running, oldest first.
+func (m ImportModel) GetUnfinished() ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, `
+        SELECT id FROM import_jobs
+        WHERE status IN ('queued', 'running')
+        ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Start marks a job as running, clearing the progress of any earlier attempt, and
+// returns it with its payload. Imports match rows by SKU, so running one again is safe.
+func (m ImportModel) Start(id int64) (*ImportJob, []byte, error) {
+	query := `
+        UPDATE import_jobs
+        SET status = 'running', started_at = NOW(), processed_rows = 0, created_rows = 0,
+            updated_rows = 0, failed_rows = 0, row_errors = '[]'
+        WHERE id = $1 AND status IN ('queued', 'running')
+        RETURNING user_id, format, total_rows, created_at, started_at, payload`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job := ImportJob{ID: id, Status: ImportRunning, RowErrors: []ImportRowError{}}
+	var payload []byte
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&job.UserID,
+		&job.Format,
+		&job.TotalRows,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&payload,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	return &job, payload, nil
+}
+
+// SaveProgress records how far a running job has got.
+func (m ImportModel) SaveProgress(job *ImportJob) error {
+	rowErrors, err := json.Marshal(job.RowErrors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE import_jobs
+        SET processed_rows = $2, created_rows = $3, updated_rows = $4, failed_rows = $5, row_errors = $6
+        WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = m.DB.ExecContext(ctx, query, job.ID, job.ProcessedRows, job.CreatedRows, job.UpdatedRows,
+		job.FailedRows, rowErrors)
+	return err
+}
+
+// Finish records a job's final state and discards its payload.
+func (m ImportModel) Finish(job *ImportJob) error {
+	rowErrors, err := json.Marshal(job.RowErrors)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE import_jobs
+        SET status = $2, processed_rows = $3, created_rows = $4, updated_rows = $5, failed_rows = $6,
+            row_errors = $7, error = $8, finished_at = NOW(), payload = ''
+        WHERE id = $1
+        RETURNING finished_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, job.ID, job.Status, job.ProcessedRows, job.CreatedRows,
+		job.UpdatedRows, job.FailedRows, rowErrors, job.Error).Scan(&job.FinishedAt)
+}
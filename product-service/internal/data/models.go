@@ -11,11 +11,35 @@ var (
 )
 
 type Models struct {
-	Products ProductModel
+	Products        ProductModel
+	Variants        VariantModel
+	Images          ImageModel
+	Categories      CategoryModel
+	Reviews         ReviewModel
+	Wishlists       WishlistModel
+	Stock           StockModel
+	Notifications   NotificationModel
+	Sellers         SellerModel
+	Imports         ImportModel
+	Recommendations RecommendationModel
+	Attributes      AttributeModel
+	Brands          BrandModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Products: ProductModel{DB: db},
+		Products:        ProductModel{DB: db},
+		Variants:        VariantModel{DB: db},
+		Images:          ImageModel{DB: db},
+		Categories:      CategoryModel{DB: db},
+		Reviews:         ReviewModel{DB: db},
+		Wishlists:       WishlistModel{DB: db},
+		Stock:           StockModel{DB: db},
+		Notifications:   NotificationModel{DB: db},
+		Sellers:         SellerModel{DB: db},
+		Imports:         ImportModel{DB: db},
+		Recommendations: RecommendationModel{DB: db},
+		Attributes:      AttributeModel{DB: db},
+		Brands:          BrandModel{DB: db},
 	}
 }
@@ -0,0 +1,270 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrDuplicateSubscription = errors.New("duplicate stock subscription")
+
+// Kinds of notification.
+const (
+	NotificationLowStock    = "low_stock"
+	NotificationBackInStock = "back_in_stock"
+)
+
+// Notification is a message waiting in the outbox to be sent to a user.
+type Notification struct {
+	ID        int64
+	UserID    int64
+	Kind      string
+	Subject   string
+	Message   string
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// StockSubscription is a shopper's request to be told when a sold-out product, or one
+// of its variants, is back in stock.
+type StockSubscription struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	VariantID *int64    `json:"variant_id,omitempty"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+// ClaimDue leases up to limit notifications that are due to be sent, so that no other
+// dispatcher picks them up for the length of the lease. Notifications that have
+// already been tried maxAttempts times are left alone.
+func (m NotificationModel) ClaimDue(limit int, lease time.Duration, maxAttempts int) ([]*Notification, error) {
+	query := `
+        UPDATE notifications
+        SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+        WHERE id IN (
+            SELECT id FROM notifications
+            WHERE sent_at IS NULL AND next_attempt_at <= NOW() AND attempts < $3
+            ORDER BY id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED)
+        RETURNING id, user_id, kind, subject, message, payload, attempts, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit, lease.Seconds(), maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Subject, &n.Message, &n.Payload, &n.Attempts, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, &n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (m NotificationModel) MarkSent(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `UPDATE notifications SET sent_at = NOW(), last_error = NULL WHERE id = $1`, id)
+	return err
+}
+
+// MarkFailed records why a notification couldn't be sent and when to try it again.
+func (m NotificationModel) MarkFailed(id int64, sendErr error, retryAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, `UPDATE notifications SET last_error = $2, next_attempt_at = $3 WHERE id = $1`,
+		id, sendErr.Error(), retryAt)
+	return err
+}
+
+// InsertSubscription adds a back-in-stock subscription. It returns
+// ErrDuplicateSubscription if the user is already waiting for the same product or
+// variant.
+func (m NotificationModel) InsertSubscription(sub *StockSubscription) error {
+	query := `
+        INSERT INTO stock_subscriptions (product_id, variant_id, user_id)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, sub.ProductID, sub.VariantID, sub.UserID).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateSubscription
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSubscriptionsForUser returns the user's subscriptions that haven't been used yet.
+func (m NotificationModel) GetSubscriptionsForUser(userID int64) ([]*StockSubscription, error) {
+	query := `
+        SELECT id, product_id, variant_id, user_id, created_at
+        FROM stock_subscriptions
+        WHERE user_id = $1 AND notified_at IS NULL
+        ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []*StockSubscription{}
+
+	for rows.Next() {
+		var sub StockSubscription
+		if err := rows.Scan(&sub.ID, &sub.ProductID, &sub.VariantID, &sub.UserID, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, &sub)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+func (m NotificationModel) DeleteSubscription(id, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM stock_subscriptions
+        WHERE id = $1 AND user_id = $2 AND notified_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// queueStockNotificationsTx queues the notifications caused by a stock movement: a
+// low-stock warning to the seller if the stock crossed the product's threshold, and a
+// back-in-stock message for each waiting subscriber if it went from nothing to
+// something. The subscriptions are used up in the same statement.
+func queueStockNotificationsTx(ctx context.Context, tx *sql.Tx, movement *StockMovement) error {
+	previous := movement.Balance - movement.Delta
+	backInStock := previous <= 0 && movement.Balance > 0
+	if movement.Delta > 0 && !backInStock {
+		return nil
+	}
+
+	var sellerID int64
+	var name, sku string
+	var threshold *int32
+
+	err := tx.QueryRowContext(ctx, `
+        SELECT p.user_id, p.name, p.low_stock_threshold, COALESCE(v.sku, '')
+        FROM products p
+        LEFT JOIN product_variants v ON v.id = $2 AND v.product_id = p.id
+        WHERE p.id = $1`, movement.ProductID, movement.VariantID).Scan(&sellerID, &name, &threshold, &sku)
+	if err != nil {
+		return err
+	}
+
+	label := name
+	if sku != "" {
+		label = fmt.Sprintf("%s (%s)", name, sku)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"product_id": movement.ProductID,
+		"variant_id": movement.VariantID,
+		"stock":      movement.Balance,
+	})
+	if err != nil {
+		return err
+	}
+
+	if threshold != nil && previous > *threshold && movement.Balance <= *threshold {
+		_, err = tx.ExecContext(ctx, `
+            INSERT INTO notifications (user_id, kind, subject, message, payload)
+            VALUES ($1, $2, $3, $4, $5)`,
+			sellerID,
+			NotificationLowStock,
+			"Low stock: "+label,
+			fmt.Sprintf("%s is down to %d in stock, at or below your threshold of %d.", label, movement.Balance, *threshold),
+			payload,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if backInStock {
+		_, err = tx.ExecContext(ctx, `
+            WITH claimed AS (
+                UPDATE stock_subscriptions
+                SET notified_at = NOW()
+                WHERE product_id = $1 AND notified_at IS NULL
+                AND (variant_id IS NULL OR variant_id = $2)
+                RETURNING user_id
+            )
+            INSERT INTO notifications (user_id, kind, subject, message, payload)
+            SELECT user_id, $3, $4, $5, $6 FROM claimed`,
+			movement.ProductID,
+			movement.VariantID,
+			NotificationBackInStock,
+			"Back in stock: "+label,
+			fmt.Sprintf("Good news: %s is back in stock.", label),
+			payload,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
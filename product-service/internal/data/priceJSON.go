@@ -2,6 +2,7 @@ package data
 
 import (
 	"errors"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -33,15 +34,23 @@ func (p *Price) UnmarshalJSON(jsonValue []byte) error {
 	if len(parts) != 2 || parts[0] != "$" {
 		return ErrInvalidPriceFormat
 	}
-	// Otherwise, parse the string containing the number into an int32. Again, if this
-	// fails return the ErrInvalidRuntimeFormat error.
-	i, err := strconv.ParseInt(parts[1], 10, 32)
+	// Otherwise, parse the string containing the number. Prices have cents, so it is
+	// parsed as a float.
+	price, err := ParsePrice(parts[1])
 	if err != nil {
-		return ErrInvalidPriceFormat
+		return err
 	}
-	// Convert the int32 to a Runtime type and assign this to the receiver. Note that we
-	// use the * operator to deference the receiver (which is a pointer to a Runtime
-	// type) in order to set the underlying value of the pointer.
-	*p = Price(i)
+	*p = price
 	return nil
 }
+
+// ParsePrice parses a plain price such as "19.99", as found in imported files. A
+// leading "$" is allowed.
+func ParsePrice(s string) (Price, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "$"))
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, ErrInvalidPriceFormat
+	}
+	return Price(f), nil
+}
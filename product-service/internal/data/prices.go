@@ -0,0 +1,153 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// productSaleActive is true while a product's sale price applies.
+const productSaleActive = `(sale_price IS NOT NULL
+	AND (sale_starts_at IS NULL OR sale_starts_at <= NOW())
+	AND (sale_ends_at IS NULL OR sale_ends_at > NOW()))`
+
+// productEffectivePrice is what a product costs right now.
+const productEffectivePrice = `(CASE WHEN ` + productSaleActive + ` THEN sale_price ELSE price END)`
+
+// productWasPrice is the price shown struck through: the compare-at price if there is
+// one, or else the regular price while the product is on sale.
+const productWasPrice = `(CASE WHEN compare_at_price IS NOT NULL THEN compare_at_price
+	WHEN ` + productSaleActive + ` THEN price END)`
+
+// productOnSale and productDiscountPercent compare the two. The discount is rounded
+// down so that it is never overstated.
+const (
+	productOnSale          = `COALESCE(` + productWasPrice + ` > ` + productEffectivePrice + `, false)`
+	productDiscountPercent = `(CASE WHEN ` + productOnSale + ` THEN floor((` + productWasPrice + ` - ` + productEffectivePrice + `) * 100 / ` + productWasPrice + `) ELSE 0 END)::integer`
+)
+
+// PriceChange is an entry in a product's price history, holding its prices from
+// CreatedAt until the next change.
+type PriceChange struct {
+	ID             int64      `json:"id"`
+	ProductID      int64      `json:"product_id"`
+	Price          Price      `json:"price"`
+	CompareAtPrice *Price     `json:"compare_at_price,omitempty"`
+	SalePrice      *Price     `json:"sale_price,omitempty"`
+	SaleStartsAt   *time.Time `json:"sale_starts_at,omitempty"`
+	SaleEndsAt     *time.Time `json:"sale_ends_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// setPricing works out the prices that depend on the time: the effective price, the
+// struck-through price and the discount between them. It must agree with the SQL
+// expressions above.
+func (p *Product) setPricing(now time.Time) {
+	saleActive := p.SalePrice != nil &&
+		(p.SaleStartsAt == nil || !p.SaleStartsAt.After(now)) &&
+		(p.SaleEndsAt == nil || p.SaleEndsAt.After(now))
+
+	p.EffectivePrice = p.Price
+	if saleActive {
+		p.EffectivePrice = *p.SalePrice
+	}
+
+	p.WasPrice = nil
+	if p.CompareAtPrice != nil {
+		was := *p.CompareAtPrice
+		p.WasPrice = &was
+	} else if saleActive {
+		was := p.Price
+		p.WasPrice = &was
+	}
+
+	p.OnSale = p.WasPrice != nil && *p.WasPrice > p.EffectivePrice
+	p.DiscountPercent = 0
+	if p.OnSale {
+		p.DiscountPercent = int(math.Floor(float64((*p.WasPrice - p.EffectivePrice) * 100 / *p.WasPrice)))
+	}
+}
+
+// samePricing reports whether two versions of a product have the same prices and sale
+// schedule.
+func samePricing(a, b *Product) bool {
+	samePrice := func(x, y *Price) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+	}
+	sameTime := func(x, y *time.Time) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && x.Equal(*y))
+	}
+	return a.Price == b.Price &&
+		samePrice(a.CompareAtPrice, b.CompareAtPrice) &&
+		samePrice(a.SalePrice, b.SalePrice) &&
+		sameTime(a.SaleStartsAt, b.SaleStartsAt) &&
+		sameTime(a.SaleEndsAt, b.SaleEndsAt)
+}
+
+// insertPriceHistoryTx records a product's current prices, as set by actorID.
+func insertPriceHistoryTx(ctx context.Context, tx *sql.Tx, product *Product, actorID int64) error {
+	query := `
+        INSERT INTO price_history (product_id, price, compare_at_price, sale_price, sale_starts_at, sale_ends_at, actor_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := tx.ExecContext(ctx, query,
+		product.ID,
+		product.Price,
+		product.CompareAtPrice,
+		product.SalePrice,
+		product.SaleStartsAt,
+		product.SaleEndsAt,
+		actorID,
+	)
+	return err
+}
+
+// GetPriceHistory returns a page of a product's price changes, newest first.
+func (m ProductModel) GetPriceHistory(productID int64, filters Filters) ([]*PriceChange, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), id, product_id, price, compare_at_price, sale_price, sale_starts_at, sale_ends_at, created_at
+        FROM price_history
+        WHERE product_id = $1
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, productID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	changes := []*PriceChange{}
+
+	for rows.Next() {
+		var change PriceChange
+		err := rows.Scan(
+			&totalRecords,
+			&change.ID,
+			&change.ProductID,
+			&change.Price,
+			&change.CompareAtPrice,
+			&change.SalePrice,
+			&change.SaleStartsAt,
+			&change.SaleEndsAt,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		changes = append(changes, &change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return changes, metadata, nil
+}
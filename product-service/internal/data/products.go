@@ -5,15 +5,44 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
 	"github.com/lib/pq"
 )
 
+// Product statuses. Only published products are shown to shoppers; sellers always
+// see their own products, whatever their status.
+const (
+	ProductDraft         = "draft"
+	ProductPendingReview = "pending_review"
+	ProductPublished     = "published"
+	ProductArchived      = "archived"
+)
+
+var ProductStatuses = []string{ProductDraft, ProductPendingReview, ProductPublished, ProductArchived}
+
+// productTransitions lists the statuses a product can be moved to from each status.
+var productTransitions = map[string][]string{
+	ProductDraft:         {ProductPendingReview, ProductPublished, ProductArchived},
+	ProductPendingReview: {ProductDraft, ProductPublished, ProductArchived},
+	ProductPublished:     {ProductDraft, ProductArchived},
+	ProductArchived:      {ProductDraft},
+}
+
+// productVisible is the condition a product must meet to be shown to shoppers. The
+// scheduler keeps the status in step with publish_at and unpublish_at, but checking
+// them here as well means nothing depends on how often it runs.
+const productVisible = `(status = 'published'
+	AND (publish_at IS NULL OR publish_at <= NOW())
+	AND (unpublish_at IS NULL OR unpublish_at > NOW()))`
+
 type Product struct {
-	ID          int64     `json:"id"`
-	UserId      int64     `json:"user_id"`
+	ID     int64 `json:"id"`
+	UserId int64 `json:"user_id"`
+	// SKU is the seller's own code for the product, unique among their products.
+	SKU         string    `json:"sku,omitempty"`
 	Name        string    `json:"name"`
 	Description string    `json:"description,omitempty"`
 	Price       Price     `json:"price,string"`
@@ -23,9 +52,164 @@ type Product struct {
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Version     int32     `json:"version"`
+
+	// Status is where the product is in its lifecycle. A published product only
+	// becomes visible at PublishAt, if set, and is archived at UnpublishAt.
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+
+	// CompareAtPrice is a reference price, such as the RRP, shown struck through.
+	// SalePrice replaces Price from SaleStartsAt until SaleEndsAt; either may be nil
+	// to leave that end of the sale open.
+	CompareAtPrice *Price     `json:"compare_at_price,omitempty"`
+	SalePrice      *Price     `json:"sale_price,omitempty"`
+	SaleStartsAt   *time.Time `json:"sale_starts_at,omitempty"`
+	SaleEndsAt     *time.Time `json:"sale_ends_at,omitempty"`
+
+	// EffectivePrice is what the product costs now, and WasPrice the price shown
+	// struck through next to it, if any. They are worked out when the product is read.
+	EffectivePrice  Price  `json:"effective_price"`
+	WasPrice        *Price `json:"was_price,omitempty"`
+	OnSale          bool   `json:"on_sale"`
+	DiscountPercent int    `json:"discount_percent,omitempty"`
+
+	// Rating is the average review rating, and ReviewCount the number of reviews it
+	// is taken over. Both are kept up to date by a trigger on product_reviews.
+	Rating      float64 `json:"rating"`
+	ReviewCount int32   `json:"review_count"`
+
+	// LowStockThreshold is the stock level at or below which the seller is warned.
+	// Nil turns the warning off.
+	LowStockThreshold *int32 `json:"low_stock_threshold,omitempty"`
+
+	// BrandID is the brand the product belongs to, if any, and Brand its name and slug.
+	BrandID *int64        `json:"brand_id,omitempty"`
+	Brand   *ProductBrand `json:"brand,omitempty"`
+
+	// Attributes holds values for the attributes defined on the product's categories,
+	// such as {"material": "cotton", "sleeve": "long"}, keyed by attribute key.
+	Attributes map[string]any `json:"attributes,omitempty"`
+
+	// Headline is an excerpt of the description with the search terms wrapped in
+	// <mark> tags. It is only set in search results.
+	Headline string `json:"headline,omitempty"`
+
+	Variants []*ProductVariant `json:"variants,omitempty"`
+	Images   []*ProductImage   `json:"images,omitempty"`
 }
 
-func ValidateProduct(v *validator.Validator, product *Product) {
+// ProductSearch holds the optional criteria the product list can be filtered by.
+// Empty values are ignored. Category holds slugs and also matches their descendants.
+type ProductSearch struct {
+	Name     string
+	Query    string
+	Category []string
+	Sizes    []string
+	Colours  []string
+	MinPrice *float64
+	MaxPrice *float64
+	InStock  bool
+	// Fuzzy matches Query against product names by trigram similarity instead of
+	// full-text search, so that misspelled searches still find something.
+	Fuzzy bool
+	// OwnerID lists that seller's products in any status instead of the visible
+	// catalogue, and ReviewQueue lists every product awaiting review. Status narrows
+	// either of them down to one status.
+	OwnerID     int64
+	ReviewQueue bool
+	Status      string
+	// SellerID limits the list to one seller's products.
+	SellerID int64
+	// OnSale limits the list to products currently priced below their was price.
+	OnSale bool
+	// Attributes limits the list to products with any of the given values for each
+	// attribute key.
+	Attributes map[string][]string
+	// Brands limits the list to products of any of the brands, by slug.
+	Brands []string
+}
+
+// productSearchConditions is the WHERE clause shared by the product list and its
+// facets. Its parameters, $1 to $16, are supplied by ProductSearch.args.
+const productSearchConditions = `(to_tsvector('english', name) @@ plainto_tsquery('english', $1) OR $1 = '')
+	AND ($5 = ''
+		OR (NOT $9 AND tsv @@ websearch_to_tsquery('english', $5))
+		OR ($9 AND lower($5) <% lower(name)))
+	AND (array_length($2::text[], 1) IS NULL OR category && ARRAY(
+		WITH RECURSIVE tree AS (
+			SELECT id, slug FROM categories WHERE slug = ANY($2)
+			UNION
+			SELECT c.id, c.slug FROM categories c JOIN tree t ON c.parent_id = t.id)
+		SELECT slug FROM tree))
+	AND ((array_length($3::text[], 1) IS NULL AND array_length($4::text[], 1) IS NULL) OR EXISTS (
+		SELECT 1 FROM product_variants v
+		WHERE v.product_id = products.id
+		AND (array_length($3::text[], 1) IS NULL OR lower(v.options->>'size') = ANY($3))
+		AND (array_length($4::text[], 1) IS NULL OR lower(v.options->>'colour') = ANY($4))))
+	AND ($6::numeric IS NULL OR ` + productEffectivePrice + ` >= $6)
+	AND ($7::numeric IS NULL OR ` + productEffectivePrice + ` <= $7)
+	AND (NOT $8 OR stock > 0 OR EXISTS (
+		SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.stock > 0))
+	AND (CASE
+		WHEN $10::bigint <> 0 THEN user_id = $10
+		WHEN $11::boolean THEN status = 'pending_review'
+		ELSE ` + productVisible + ` END)
+	AND ($12 = '' OR status = $12)
+	AND ($13::bigint = 0 OR user_id = $13)
+	AND (NOT $14::boolean OR ` + productOnSale + `)
+	AND ($15 = '' OR attributes @@ NULLIF($15, '')::jsonpath)
+	AND (array_length($16::text[], 1) IS NULL OR brand_id IN (SELECT id FROM brands WHERE slug = ANY($16)))`
+
+func (s ProductSearch) args() []interface{} {
+	return []interface{}{
+		s.Name,
+		pq.Array(s.Category),
+		pq.Array(lowerAll(s.Sizes)),
+		pq.Array(lowerAll(s.Colours)),
+		s.Query,
+		s.MinPrice,
+		s.MaxPrice,
+		s.InStock,
+		s.Fuzzy,
+		s.OwnerID,
+		s.ReviewQueue,
+		s.Status,
+		s.SellerID,
+		s.OnSale,
+		attributesPath(s.Attributes),
+		pq.Array(s.Brands),
+	}
+}
+
+func ValidateProductSearch(v *validator.Validator, search ProductSearch, filters Filters) {
+	v.Check(len(search.Query) <= 200, "q", "must not exceed 200 characters")
+	if search.MinPrice != nil {
+		v.Check(*search.MinPrice >= 0, "min_price", "must be zero or greater")
+	}
+	if search.MaxPrice != nil {
+		v.Check(*search.MaxPrice >= 0, "max_price", "must be zero or greater")
+	}
+	if search.MinPrice != nil && search.MaxPrice != nil {
+		v.Check(*search.MaxPrice >= *search.MinPrice, "max_price", "must not be less than min_price")
+	}
+	v.Check(filters.Sort != "relevance" || search.Query != "", "sort", "relevance sorting requires a q search")
+	v.Check(!search.Fuzzy || search.Query != "", "fuzzy", "requires a q search")
+	v.Check(len(search.Brands) <= 20, "brand", "must not contain more than 20 brands")
+	ValidateAttributeFilters(v, search.Attributes)
+	if search.Status != "" {
+		v.Check(validator.In(search.Status, ProductStatuses...), "status", "must be one of draft, pending_review, published or archived")
+	}
+}
+
+// ValidateProduct checks a product, including its attribute values against the
+// definitions that apply to its categories.
+func ValidateProduct(v *validator.Validator, product *Product, definitions []*AttributeDefinition) {
+	if product.SKU != "" {
+		v.Check(len(product.SKU) <= 100, "sku", "must not exceed 100 characters")
+		v.Check(validator.Matches(product.SKU, skuRX), "sku", "must contain only letters, digits, dots, underscores and hyphens")
+	}
+
 	v.Check(product.Name != "", "name", "must be provided")
 	v.Check(len(product.Name) <= 255, "name", "must not exceed 255 characters")
 
@@ -35,26 +219,95 @@ func ValidateProduct(v *validator.Validator, product *Product) {
 	v.Check(product.Price > 0, "price", "must be greater than zero")
 	v.Check(product.Price < 1000000, "price", "must be less than 1,000,000")
 
-	v.Check(product.ImageUrl != "", "image_url", "must be provided")
+	if product.CompareAtPrice != nil {
+		v.Check(*product.CompareAtPrice > product.Price, "compare_at_price", "must be greater than price")
+		v.Check(*product.CompareAtPrice < 1000000, "compare_at_price", "must be less than 1,000,000")
+	}
+	if product.SalePrice != nil {
+		v.Check(*product.SalePrice > 0, "sale_price", "must be greater than zero")
+		v.Check(*product.SalePrice < product.Price, "sale_price", "must be less than price")
+	} else {
+		v.Check(product.SaleStartsAt == nil, "sale_starts_at", "requires a sale_price")
+		v.Check(product.SaleEndsAt == nil, "sale_ends_at", "requires a sale_price")
+	}
+	if product.SaleStartsAt != nil && product.SaleEndsAt != nil {
+		v.Check(product.SaleEndsAt.After(*product.SaleStartsAt), "sale_ends_at", "must be later than sale_starts_at")
+	}
+
+	// image_url may be left empty when the seller uploads a gallery instead; it is then
+	// set to the primary image.
 	v.Check(len(product.ImageUrl) <= 1000, "image_url", "must not exceed 1000 characters")
 
 	v.Check(product.Stock >= 0, "stock", "must be zero or greater")
+	if product.LowStockThreshold != nil {
+		v.Check(*product.LowStockThreshold >= 0, "low_stock_threshold", "must be zero or greater")
+		v.Check(*product.LowStockThreshold < 1000000, "low_stock_threshold", "must be less than 1,000,000")
+	}
 
 	v.Check(len(product.Category) > 0, "category", "must have at least one category")
 	v.Check(len(product.Category) <= 5, "category", "must not exceed 5 categories")
 	v.Check(validator.Unique(product.Category), "category", "must not contain duplicate values")
+
+	v.Check(validator.In(product.Status, ProductStatuses...), "status", "must be one of draft, pending_review, published or archived")
+	if product.PublishAt != nil && product.UnpublishAt != nil {
+		v.Check(product.UnpublishAt.After(*product.PublishAt), "unpublish_at", "must be later than publish_at")
+	}
+
+	if product.BrandID != nil {
+		v.Check(*product.BrandID > 0, "brand_id", "must be a positive integer")
+	}
+
+	validateProductAttributes(v, product.Attributes, definitions)
+}
+
+// ValidateStatusChange checks that a product can be moved from one status to another.
+func ValidateStatusChange(v *validator.Validator, from, to string) {
+	if from == to {
+		return
+	}
+	v.Check(validator.In(to, productTransitions[from]...), "status", fmt.Sprintf("cannot be changed from %s to %s", from, to))
+}
+
+// Visible reports whether shoppers can see the product at the given time.
+func (p *Product) Visible(now time.Time) bool {
+	return p.Status == ProductPublished &&
+		(p.PublishAt == nil || !p.PublishAt.After(now)) &&
+		(p.UnpublishAt == nil || p.UnpublishAt.After(now))
+}
+
+// LastModified is when the product, as shown to shoppers, last changed. That is its
+// updated_at, which triggers keep in step with its reviews, variants and images, unless
+// a sale has started or ended since.
+func (p *Product) LastModified(now time.Time) time.Time {
+	modified := p.UpdatedAt
+	for _, t := range []*time.Time{p.SaleStartsAt, p.SaleEndsAt} {
+		if t != nil && !t.After(now) && t.After(modified) {
+			modified = *t
+		}
+	}
+	return modified
 }
 
 type ProductModel struct {
 	DB *sql.DB
+	// Cache, if set, holds products and public product lists read recently.
+	Cache *CatalogueCache
 }
 
-// Add a placeholder method for inserting a new record in the product table.
+// Insert adds a product. Its opening stock and prices are recorded in the stock ledger
+// and price history in the same transaction.
 func (m ProductModel) Insert(product *Product) error {
 	query := `
-        INSERT INTO products (user_id, name, description, price, image_url, stock, category)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
-        RETURNING id, created_at, updated_at, version`
+        INSERT INTO products (user_id, name, description, price, image_url, stock, category, low_stock_threshold,
+            status, publish_at, unpublish_at, sku, compare_at_price, sale_price, sale_starts_at, sale_ends_at, attributes, brand_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $14, $15, $16, $17, $18)
+        RETURNING id, created_at, updated_at, version,
+            (SELECT b.name FROM brands b WHERE b.id = products.brand_id), (SELECT b.slug FROM brands b WHERE b.id = products.brand_id)`
+
+	attributes, err := marshalAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
 
 	args := []interface{}{
 		product.UserId,
@@ -64,17 +317,74 @@ func (m ProductModel) Insert(product *Product) error {
 		product.ImageUrl,
 		product.Stock,
 		pq.Array(product.Category),
+		product.LowStockThreshold,
+		product.Status,
+		product.PublishAt,
+		product.UnpublishAt,
+		product.SKU,
+		product.CompareAtPrice,
+		product.SalePrice,
+		product.SaleStartsAt,
+		product.SaleEndsAt,
+		attributes,
+		product.BrandID,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var brandName, brandSlug sql.NullString
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
 		&product.ID,
 		&product.CreatedAt,
 		&product.UpdatedAt,
 		&product.Version,
+		&brandName,
+		&brandSlug,
 	)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateSKU
+		case isUnknownBrand(err):
+			return ErrUnknownBrand
+		default:
+			return err
+		}
+	}
+
+	if product.Stock != 0 {
+		err = insertStockMovementTx(ctx, tx, &StockMovement{
+			ProductID: product.ID,
+			Delta:     product.Stock,
+			Reason:    StockInitial,
+			ActorID:   &product.UserId,
+			Balance:   product.Stock,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = insertPriceHistoryTx(ctx, tx, product, product.UserId)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	m.Cache.InvalidateLists()
+	product.setBrand(brandName, brandSlug)
+	product.setPricing(time.Now())
+	return nil
 }
 
 // if product.Stock <= 0 { return errOutOfStock }
@@ -84,12 +394,22 @@ func (m ProductModel) Get(id int64) (*Product, error) {
 		return nil, ErrRecordNotFound
 	}
 
+	if product, ok := m.Cache.getProduct(id, time.Now()); ok {
+		return product, nil
+	}
+	generation := m.Cache.productGeneration()
+
 	query := `
-	SELECT id, user_id, name, description, price, image_url, stock, category, created_at, updated_at, version 
+	SELECT id, user_id, COALESCE(sku, ''), name, description, price, image_url, stock, category, created_at, updated_at, version,
+		rating_average, rating_count, low_stock_threshold, status, publish_at, unpublish_at,
+		compare_at_price, sale_price, sale_starts_at, sale_ends_at, attributes, brand_id,
+		(SELECT b.name FROM brands b WHERE b.id = products.brand_id), (SELECT b.slug FROM brands b WHERE b.id = products.brand_id)
 	FROM products
 	WHERE id = $1`
 
 	var product Product
+	var attributes []byte
+	var brandName, brandSlug sql.NullString
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	// Importantly, use defer to make sure that we cancel the context before the Get()
@@ -99,6 +419,7 @@ func (m ProductModel) Get(id int64) (*Product, error) {
 	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&product.ID,
 		&product.UserId,
+		&product.SKU,
 		&product.Name,
 		&product.Description,
 		&product.Price,
@@ -108,6 +429,20 @@ func (m ProductModel) Get(id int64) (*Product, error) {
 		&product.CreatedAt,
 		&product.UpdatedAt,
 		&product.Version,
+		&product.Rating,
+		&product.ReviewCount,
+		&product.LowStockThreshold,
+		&product.Status,
+		&product.PublishAt,
+		&product.UnpublishAt,
+		&product.CompareAtPrice,
+		&product.SalePrice,
+		&product.SaleStartsAt,
+		&product.SaleEndsAt,
+		&attributes,
+		&product.BrandID,
+		&brandName,
+		&brandSlug,
 	)
 
 	if err != nil {
@@ -119,18 +454,144 @@ func (m ProductModel) Get(id int64) (*Product, error) {
 		}
 	}
 
+	if err := unmarshalAttributes(attributes, &product.Attributes); err != nil {
+		return nil, err
+	}
+	product.setBrand(brandName, brandSlug)
+
+	product.setPricing(time.Now())
+	m.Cache.addProduct(&product, generation)
+
 	return &product, nil
 }
 
-// Add a placeholder method for updating a specific record in the products table.
-func (m ProductModel) Update(product *Product) error {
+// GetBySKU fetches one of a seller's products by its SKU.
+func (m ProductModel) GetBySKU(userID int64, sku string) (*Product, error) {
+	var id int64
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, `SELECT id FROM products WHERE user_id = $1 AND sku = $2`, userID, sku).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return m.Get(id)
+}
+
+// ForEachOwned calls fn with each of a seller's products in turn, in ID order, without
+// loading them all into memory. It stops at the first error fn returns. ctx bounds the
+// whole iteration, since a large catalogue can take a while to stream.
+func (m ProductModel) ForEachOwned(ctx context.Context, userID int64, fn func(*Product) error) error {
+	query := `
+	SELECT id, user_id, COALESCE(sku, ''), name, description, price, image_url, stock, category, created_at, updated_at, version,
+		low_stock_threshold, status, publish_at, unpublish_at, attributes
+	FROM products
+	WHERE user_id = $1
+	ORDER BY id`
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product Product
+		var attributes []byte
+
+		err := rows.Scan(
+			&product.ID,
+			&product.UserId,
+			&product.SKU,
+			&product.Name,
+			&product.Description,
+			&product.Price,
+			&product.ImageUrl,
+			&product.Stock,
+			pq.Array(&product.Category),
+			&product.CreatedAt,
+			&product.UpdatedAt,
+			&product.Version,
+			&product.LowStockThreshold,
+			&product.Status,
+			&product.PublishAt,
+			&product.UnpublishAt,
+			&attributes,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := unmarshalAttributes(attributes, &product.Attributes); err != nil {
+			return err
+		}
+
+		if err := fn(&product); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Update saves a product. Stock isn't overwritten directly: any difference from the
+// stored stock is recorded in the ledger as an adjustment by actorID, in the same
+// transaction. If any of the prices changed, the new ones are added to the price
+// history.
+func (m ProductModel) Update(product *Product, actorID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stock int32
+	var old Product
+	err = tx.QueryRowContext(ctx, `
+	SELECT stock, price, compare_at_price, sale_price, sale_starts_at, sale_ends_at
+	FROM products
+	WHERE id = $1 AND version = $2
+	FOR UPDATE`, product.ID, product.Version).Scan(
+		&stock,
+		&old.Price,
+		&old.CompareAtPrice,
+		&old.SalePrice,
+		&old.SaleStartsAt,
+		&old.SaleEndsAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
 
 	query := `
 	UPDATE products
     SET name = $1, description = $2, price = $3, image_url = $4, 
-        stock = $5, category = $6, updated_at = NOW(), version = version + 1
-    WHERE id = $7 AND version = $8
-    RETURNING version`
+        stock = $5, category = $6, low_stock_threshold = $7, status = $8, publish_at = $9, unpublish_at = $10,
+        sku = NULLIF($11, ''), compare_at_price = $12, sale_price = $13, sale_starts_at = $14, sale_ends_at = $15,
+        attributes = $16, brand_id = $17, updated_at = NOW(), version = version + 1
+    WHERE id = $18 AND version = $19
+    RETURNING version, updated_at,
+        (SELECT b.name FROM brands b WHERE b.id = products.brand_id), (SELECT b.slug FROM brands b WHERE b.id = products.brand_id)`
+
+	attributes, err := marshalAttributes(product.Attributes)
+	if err != nil {
+		return err
+	}
 
 	args := []interface{}{
 		product.Name,
@@ -139,40 +600,82 @@ func (m ProductModel) Update(product *Product) error {
 		product.ImageUrl,
 		product.Stock,
 		pq.Array(product.Category),
+		product.LowStockThreshold,
+		product.Status,
+		product.PublishAt,
+		product.UnpublishAt,
+		product.SKU,
+		product.CompareAtPrice,
+		product.SalePrice,
+		product.SaleStartsAt,
+		product.SaleEndsAt,
+		attributes,
+		product.BrandID,
 		product.ID,
 		product.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	// Use QueryRowContext() and pass the context as the first argument.
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&product.Version, &product.UpdatedAt)
+	var brandName, brandSlug sql.NullString
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&product.Version, &product.UpdatedAt, &brandName, &brandSlug)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
+		case isUniqueViolation(err):
+			return ErrDuplicateSKU
+		case isUnknownBrand(err):
+			return ErrUnknownBrand
 		default:
 			return err
 		}
 	}
+
+	if delta := product.Stock - stock; delta != 0 {
+		err = insertStockMovementTx(ctx, tx, &StockMovement{
+			ProductID: product.ID,
+			Delta:     delta,
+			Reason:    StockAdjustment,
+			ActorID:   &actorID,
+			Balance:   product.Stock,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !samePricing(&old, product) {
+		err = insertPriceHistoryTx(ctx, tx, product, actorID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	m.Cache.Invalidate(product.ID)
+	product.setBrand(brandName, brandSlug)
+
+	product.setPricing(time.Now())
 	return nil
 }
 
-// Add a placeholder method for deleting a specific record from the products table.
-func (m ProductModel) Delete(id int64) error {
+// Archive takes a product off sale in place of deleting it, since past orders, reviews
+// and the stock ledger still refer to it. Any schedule is cleared.
+func (m ProductModel) Archive(id int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
-	DELETE FROM products
+	UPDATE products
+	SET status = 'archived', publish_at = NULL, unpublish_at = NULL, updated_at = NOW(), version = version + 1
 	WHERE id = $1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	// Use ExecContext() and pass the context as the first argument.
 	result, err := m.DB.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
@@ -187,23 +690,100 @@ func (m ProductModel) Delete(id int64) error {
 		return ErrRecordNotFound
 	}
 
+	m.Cache.Invalidate(id)
 	return nil
 }
 
-func (m ProductModel) GetAll(name string, category []string, filters Filters) ([]*Product, Metadata, error) {
+// ApplySchedule brings product statuses up to date with their schedules. Published
+// products whose publish_at has passed go live, which clears it; those whose
+// unpublish_at has passed are archived. It returns how many of each there were.
+func (m ProductModel) ApplySchedule() (published, archived int64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, `
+	UPDATE products
+	SET status = 'archived', publish_at = NULL, unpublish_at = NULL, updated_at = NOW(), version = version + 1
+	WHERE status = 'published' AND unpublish_at <= NOW()`)
+	if err != nil {
+		return 0, 0, err
+	}
+	if archived, err = result.RowsAffected(); err != nil {
+		return 0, 0, err
+	}
+
+	result, err = m.DB.ExecContext(ctx, `
+	UPDATE products
+	SET publish_at = NULL, updated_at = NOW(), version = version + 1
+	WHERE status = 'published' AND publish_at <= NOW()`)
+	if err != nil {
+		return 0, archived, err
+	}
+	if published, err = result.RowsAffected(); err != nil {
+		return 0, archived, err
+	}
+
+	if published > 0 || archived > 0 {
+		m.Cache.InvalidateAll()
+	}
+	return published, archived, nil
+}
+
+// GetAll returns a page of the products matching search. Pages are read from
+// filters.Cursor if it is set, and by offset otherwise; either way one more product
+// than the page size is read, to tell whether there is a next page. The matches are
+// only counted if filters.WithTotal is set.
+func (m ProductModel) GetAll(search ProductSearch, filters Filters) ([]*Product, Metadata, error) {
+	cacheKey := listCacheKey(search, filters)
+	if products, metadata, ok := m.Cache.getList(cacheKey, time.Now()); ok {
+		return products, metadata, nil
+	}
+	generation := m.Cache.listGeneration()
+
+	total := "0"
+	if filters.WithTotal {
+		total = "count(*) OVER()"
+	}
+	keyset, keysetArgs := filters.keyset(filters.sortColumn(), 19)
+
+	// The innermost query finds the matches, the middle one pages them, and the outer
+	// one adds snippets to the page being returned, since ts_headline is expensive.
+	// The matches are paged by a separate query so that the cursor's condition can
+	// refer to the computed sort columns, and so that it doesn't affect the count.
 	query := fmt.Sprintf(`
-	SELECT count(*) OVER(), id, name, description, price, image_url, stock, category, created_at, updated_at, version
-	FROM products
-	WHERE (to_tsvector('english', name) @@ plainto_tsquery('english', $1) OR $1 = '') 
-	AND (array_length($2::text[], 1) IS NULL OR category && $2)
-	ORDER BY %s %s, id ASC
-	LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+	SELECT p.total, p.id, p.user_id, p.sku, p.name, p.description, p.price, p.image_url, p.stock, p.category,
+		p.created_at, p.updated_at, p.version, p.status, p.publish_at, p.unpublish_at, p.rating_average, p.rating_count,
+		p.compare_at_price, p.sale_price, p.sale_starts_at, p.sale_ends_at, p.attributes, p.brand_id, b.name, b.slug,
+		CASE WHEN $5 = '' OR $9 THEN '' ELSE ts_headline('english', p.description, websearch_to_tsquery('english', $5),
+			'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5') END,
+		p.%[1]s::text
+	FROM (
+		SELECT * FROM (
+			SELECT %[6]s AS total, id, user_id, COALESCE(sku, '') AS sku, name, description, price, image_url, stock, category,
+				created_at, updated_at, version, status, publish_at, unpublish_at, rating_average, rating_count,
+				compare_at_price, sale_price, sale_starts_at, sale_ends_at, attributes, brand_id,
+				%[4]s AS effective_price, %[5]s AS discount_percent,
+				CASE
+					WHEN $5 = '' THEN 0
+					WHEN $9 THEN word_similarity(lower($5), lower(name))
+					ELSE ts_rank_cd(tsv, websearch_to_tsquery('english', $5))
+				END AS relevance
+			FROM products
+			WHERE %[3]s
+		) AS matches
+		WHERE %[7]s
+		ORDER BY %[1]s %[2]s, id ASC
+		LIMIT $17 OFFSET $18
+	) AS p
+	LEFT JOIN brands b ON b.id = p.brand_id
+	ORDER BY p.%[1]s %[2]s, p.id ASC`, filters.sortColumn(), filters.sortDirection(), productSearchConditions,
+		productEffectivePrice, productDiscountPercent, total, keyset)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []interface{}{name, pq.Array(category), filters.limit(), filters.offset()}
-	fmt.Printf("Query: %s\nArgs: %v\n", query, args) // Debug logging
+	args := append(search.args(), filters.limit()+1, filters.offset())
+	args = append(args, keysetArgs...)
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -213,13 +793,20 @@ func (m ProductModel) GetAll(name string, category []string, filters Filters) ([
 
 	totalRecords := 0
 	products := []*Product{}
+	sortKeys := []string{}
+	now := time.Now()
 
 	for rows.Next() {
 		var product Product
+		var attributes []byte
+		var brandName, brandSlug sql.NullString
+		var sortKey string
 
 		err := rows.Scan(
 			&totalRecords,
 			&product.ID,
+			&product.UserId,
+			&product.SKU,
 			&product.Name,
 			&product.Description,
 			&product.Price,
@@ -229,20 +816,66 @@ func (m ProductModel) GetAll(name string, category []string, filters Filters) ([
 			&product.CreatedAt,
 			&product.UpdatedAt,
 			&product.Version,
+			&product.Status,
+			&product.PublishAt,
+			&product.UnpublishAt,
+			&product.Rating,
+			&product.ReviewCount,
+			&product.CompareAtPrice,
+			&product.SalePrice,
+			&product.SaleStartsAt,
+			&product.SaleEndsAt,
+			&attributes,
+			&product.BrandID,
+			&brandName,
+			&brandSlug,
+			&product.Headline,
+			&sortKey,
 		)
 
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
+		if err := unmarshalAttributes(attributes, &product.Attributes); err != nil {
+			return nil, Metadata{}, err
+		}
+		product.setBrand(brandName, brandSlug)
+
+		product.setPricing(now)
 		products = append(products, &product)
+		sortKeys = append(sortKeys, sortKey)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
 
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	nextCursor := ""
+	if len(products) > filters.PageSize {
+		products = products[:filters.PageSize]
+		last := products[len(products)-1]
+		nextCursor = encodeCursor(filters.Sort, sortKeys[len(products)-1], last.ID)
+	}
+
+	metadata := filters.metadata(totalRecords, nextCursor)
+	m.Cache.addList(cacheKey, products, metadata, generation)
 
 	return products, metadata, nil
 }
+
+// setBrand sets the product's brand from the columns joined from brands.
+func (p *Product) setBrand(name, slug sql.NullString) {
+	p.Brand = nil
+	if p.BrandID != nil && name.Valid {
+		p.Brand = &ProductBrand{Name: name.String, Slug: slug.String}
+	}
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(value))
+	}
+	return lowered
+}
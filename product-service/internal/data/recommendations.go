@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+// MaxRecommendations is the most products a recommendation list can return.
+const MaxRecommendations = 50
+
+// CoPurchase is the number of orders in which two products were bought together, as
+// reported by order-service.
+type CoPurchase struct {
+	ProductID      int64 `json:"product_id"`
+	OtherProductID int64 `json:"other_product_id"`
+	Orders         int   `json:"orders"`
+}
+
+// recommendedProductColumns are the columns recommendation lists select from products,
+// aliased as p, in the order scanRecommendedProduct reads them.
+const recommendedProductColumns = `p.id, p.user_id, COALESCE(p.sku, ''), p.name, p.description, p.price, p.image_url,
+	p.stock, p.category, p.created_at, p.updated_at, p.version, p.status, p.publish_at, p.unpublish_at,
+	p.rating_average, p.rating_count, p.compare_at_price, p.sale_price, p.sale_starts_at, p.sale_ends_at`
+
+func scanRecommendedProduct(row rowScanner, now time.Time) (*Product, error) {
+	var product Product
+
+	err := row.Scan(
+		&product.ID,
+		&product.UserId,
+		&product.SKU,
+		&product.Name,
+		&product.Description,
+		&product.Price,
+		&product.ImageUrl,
+		&product.Stock,
+		pq.Array(&product.Category),
+		&product.CreatedAt,
+		&product.UpdatedAt,
+		&product.Version,
+		&product.Status,
+		&product.PublishAt,
+		&product.UnpublishAt,
+		&product.Rating,
+		&product.ReviewCount,
+		&product.CompareAtPrice,
+		&product.SalePrice,
+		&product.SaleStartsAt,
+		&product.SaleEndsAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	product.setPricing(now)
+	return &product, nil
+}
+
+func ValidateRecommendationLimit(v *validator.Validator, limit int) {
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= MaxRecommendations, "limit", "must be a maximum of 50")
+}
+
+type RecommendationModel struct {
+	DB *sql.DB
+}
+
+// GetSimilar returns up to limit visible products like the given one. Products are
+// ranked by the share of its categories they have, plus how well they match the words
+// in its name, so a product only needs one or the other to be listed.
+func (m RecommendationModel) GetSimilar(productID int64, limit int) ([]*Product, error) {
+	// plainto_tsquery ANDs the words of the name together; ORing them instead matches
+	// products that share any of them.
+	query := `
+	WITH src AS (
+		SELECT id, category,
+			NULLIF(replace(plainto_tsquery('english', name)::text, ' & ', ' | '), '')::tsquery AS q
+		FROM products
+		WHERE id = $1
+	)
+	SELECT ` + recommendedProductColumns + `
+	FROM products p, src
+	WHERE p.id <> src.id AND ` + productVisible + `
+	AND (p.category && src.category OR p.tsv @@ src.q)
+	ORDER BY
+		cardinality(ARRAY(SELECT unnest(p.category) INTERSECT SELECT unnest(src.category)))::float
+			/ GREATEST(cardinality(src.category), 1)
+		+ COALESCE(ts_rank(p.tsv, src.q), 0) DESC,
+		p.rating_average DESC, p.id ASC
+	LIMIT $2`
+
+	return m.list(query, productID, limit)
+}
+
+// GetBoughtTogether returns up to limit visible products that have been ordered along
+// with the given one, most often first.
+func (m RecommendationModel) GetBoughtTogether(productID int64, limit int) ([]*Product, error) {
+	query := `
+	SELECT ` + recommendedProductColumns + `
+	FROM bought_together bt
+	JOIN products p ON p.id = bt.other_product_id
+	WHERE bt.product_id = $1 AND ` + productVisible + `
+	ORDER BY bt.orders DESC, p.id ASC
+	LIMIT $2`
+
+	return m.list(query, productID, limit)
+}
+
+func (m RecommendationModel) list(query string, productID int64, limit int) ([]*Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, productID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	products := []*Product{}
+
+	for rows.Next() {
+		product, err := scanRecommendedProduct(rows, now)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// GetCoPurchaseCursor returns the ID of the last order-service order item that has been
+// added to bought_together.
+func (m RecommendationModel) GetCoPurchaseCursor() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var cursor int64
+	err := m.DB.QueryRowContext(ctx, `SELECT last_order_item_id FROM co_purchase_sync`).Scan(&cursor)
+	return cursor, err
+}
+
+// AddCoPurchases adds the pairs read from order-service for the order items after from,
+// up to and including to, and moves the cursor on to to. It returns ErrEditConflict,
+// and changes nothing, if the cursor is no longer at from because another instance got
+// there first. Pairs involving products that don't exist here are skipped.
+func (m RecommendationModel) AddCoPurchases(pairs []CoPurchase, from, to int64) error {
+	// Each pair is counted in both directions, and may have been reported both ways
+	// round, so they are added up before they are saved.
+	type key struct{ product, other int64 }
+	counts := make(map[key]int)
+	for _, pair := range pairs {
+		counts[key{pair.ProductID, pair.OtherProductID}] += pair.Orders
+		counts[key{pair.OtherProductID, pair.ProductID}] += pair.Orders
+	}
+
+	productIDs := make([]int64, 0, len(counts))
+	otherIDs := make([]int64, 0, len(counts))
+	orders := make([]int64, 0, len(counts))
+	for k, n := range counts {
+		productIDs = append(productIDs, k.product)
+		otherIDs = append(otherIDs, k.other)
+		orders = append(orders, int64(n))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE co_purchase_sync
+	SET last_order_item_id = $2, synced_at = NOW()
+	WHERE last_order_item_id = $1`, from, to)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	if len(counts) > 0 {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO bought_together (product_id, other_product_id, orders)
+		SELECT t.product_id, t.other_product_id, t.orders
+		FROM unnest($1::bigint[], $2::bigint[], $3::integer[]) AS t(product_id, other_product_id, orders)
+		WHERE EXISTS (SELECT 1 FROM products WHERE id = t.product_id)
+		AND EXISTS (SELECT 1 FROM products WHERE id = t.other_product_id)
+		ON CONFLICT (product_id, other_product_id)
+		DO UPDATE SET orders = bought_together.orders + EXCLUDED.orders, updated_at = NOW()`,
+			pq.Array(productIDs), pq.Array(otherIDs), pq.Array(orders))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
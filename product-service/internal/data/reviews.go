@@ -0,0 +1,321 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateReview = errors.New("duplicate review")
+	ErrDuplicateReport = errors.New("duplicate report")
+)
+
+// MaxReviewPhotos is the most photos a review can have.
+const MaxReviewPhotos = 5
+
+// Review is a verified buyer's rating of a product. OrderID is the delivered order
+// the purchase was checked against; it isn't shown publicly.
+type Review struct {
+	ID              int64      `json:"id"`
+	ProductID       int64      `json:"product_id"`
+	UserID          int64      `json:"user_id"`
+	OrderID         int64      `json:"-"`
+	Rating          int16      `json:"rating"`
+	Title           string     `json:"title"`
+	Body            string     `json:"body,omitempty"`
+	PhotoURLs       []string   `json:"photo_urls"`
+	SellerReply     *string    `json:"seller_reply,omitempty"`
+	SellerRepliedAt *time.Time `json:"seller_replied_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Version         int32      `json:"version"`
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Rating >= 1 && review.Rating <= 5, "rating", "must be between 1 and 5")
+
+	v.Check(review.Title != "", "title", "must be provided")
+	v.Check(len(review.Title) <= 150, "title", "must not exceed 150 characters")
+
+	v.Check(len(review.Body) <= 5000, "body", "must not exceed 5000 characters")
+
+	v.Check(len(review.PhotoURLs) <= MaxReviewPhotos, "photo_urls", "must not contain more than 5 photos")
+	v.Check(validator.Unique(review.PhotoURLs), "photo_urls", "must not contain duplicate values")
+	for _, photo := range review.PhotoURLs {
+		v.Check(len(photo) <= 1000 && validator.IsURL(photo), "photo_urls", "must contain only valid URLs")
+	}
+}
+
+func ValidateReviewReply(v *validator.Validator, reply string) {
+	v.Check(reply != "", "reply", "must be provided")
+	v.Check(len(reply) <= 2000, "reply", "must not exceed 2000 characters")
+}
+
+func ValidateReviewReport(v *validator.Validator, reason string) {
+	v.Check(reason != "", "reason", "must be provided")
+	v.Check(len(reason) <= 500, "reason", "must not exceed 500 characters")
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+// Insert adds a review. It returns ErrDuplicateReview if the user has already reviewed
+// the product.
+func (m ReviewModel) Insert(review *Review) error {
+	query := `
+        INSERT INTO product_reviews (product_id, user_id, order_id, rating, title, body, photo_urls)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []interface{}{
+		review.ProductID,
+		review.UserID,
+		review.OrderID,
+		review.Rating,
+		review.Title,
+		review.Body,
+		pq.Array(review.PhotoURLs),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&review.ID,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Version,
+	)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateReview
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a review of the given product.
+func (m ReviewModel) Get(id, productID int64) (*Review, error) {
+	if id < 1 || productID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, product_id, user_id, order_id, rating, title, body, photo_urls,
+               seller_reply, seller_replied_at, created_at, updated_at, version
+        FROM product_reviews
+        WHERE id = $1 AND product_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	review, err := scanReview(m.DB.QueryRowContext(ctx, query, id, productID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return review, nil
+}
+
+// GetAllForProduct returns a page of a product's reviews, optionally only those with
+// the given rating.
+func (m ReviewModel) GetAllForProduct(productID int64, rating int16, filters Filters) ([]*Review, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), id, product_id, user_id, order_id, rating, title, body, photo_urls,
+               seller_reply, seller_replied_at, created_at, updated_at, version
+        FROM product_reviews
+        WHERE product_id = $1 AND ($2::int = 0 OR rating = $2)
+        ORDER BY ` + filters.reviewSortColumn() + ` ` + filters.sortDirection() + `, id DESC
+        LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, productID, rating, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+		err := rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.ProductID,
+			&review.UserID,
+			&review.OrderID,
+			&review.Rating,
+			&review.Title,
+			&review.Body,
+			pq.Array(&review.PhotoURLs),
+			&review.SellerReply,
+			&review.SellerRepliedAt,
+			&review.CreatedAt,
+			&review.UpdatedAt,
+			&review.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reviews = append(reviews, &review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reviews, metadata, nil
+}
+
+// Update saves the author's changes to a review.
+func (m ReviewModel) Update(review *Review) error {
+	query := `
+        UPDATE product_reviews
+        SET rating = $1, title = $2, body = $3, photo_urls = $4, updated_at = NOW(), version = version + 1
+        WHERE id = $5 AND version = $6
+        RETURNING updated_at, version`
+
+	args := []interface{}{
+		review.Rating,
+		review.Title,
+		review.Body,
+		pq.Array(review.PhotoURLs),
+		review.ID,
+		review.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&review.UpdatedAt, &review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Reply sets, or replaces, the seller's public reply to a review.
+func (m ReviewModel) Reply(review *Review, reply string) error {
+	query := `
+        UPDATE product_reviews
+        SET seller_reply = $1, seller_replied_at = NOW(), version = version + 1
+        WHERE id = $2 AND version = $3
+        RETURNING seller_reply, seller_replied_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, reply, review.ID, review.Version).Scan(
+		&review.SellerReply,
+		&review.SellerRepliedAt,
+		&review.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m ReviewModel) Delete(id, productID int64) error {
+	if id < 1 || productID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM product_reviews
+        WHERE id = $1 AND product_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, productID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Report records a user's abuse report against a review. Each user can report a
+// review once; a second report returns ErrDuplicateReport.
+func (m ReviewModel) Report(reviewID, reporterID int64, reason string) error {
+	query := `
+        INSERT INTO review_reports (review_id, reporter_id, reason)
+        VALUES ($1, $2, $3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, reviewID, reporterID, reason)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateReport
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func scanReview(row rowScanner) (*Review, error) {
+	var review Review
+	err := row.Scan(
+		&review.ID,
+		&review.ProductID,
+		&review.UserID,
+		&review.OrderID,
+		&review.Rating,
+		&review.Title,
+		&review.Body,
+		pq.Array(&review.PhotoURLs),
+		&review.SellerReply,
+		&review.SellerRepliedAt,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
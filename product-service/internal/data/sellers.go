@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+// Seller is a seller's storefront profile. ProductCount is the number of their
+// products shoppers can currently see.
+type Seller struct {
+	UserID       int64     `json:"user_id"`
+	Slug         string    `json:"slug"`
+	DisplayName  string    `json:"display_name"`
+	Bio          string    `json:"bio,omitempty"`
+	LogoURL      string    `json:"logo_url,omitempty"`
+	ReturnPolicy string    `json:"return_policy,omitempty"`
+	ProductCount int       `json:"product_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Version      int32     `json:"version"`
+}
+
+func ValidateSeller(v *validator.Validator, seller *Seller) {
+	v.Check(seller.DisplayName != "", "display_name", "must be provided")
+	v.Check(len(seller.DisplayName) <= 100, "display_name", "must not exceed 100 characters")
+
+	v.Check(seller.Slug != "", "slug", "must be provided")
+	v.Check(len(seller.Slug) <= 100, "slug", "must not exceed 100 characters")
+	v.Check(validator.Matches(seller.Slug, slugRX), "slug", "must contain only lowercase letters, digits and single hyphens")
+	// "me" is the route sellers manage their own profile through.
+	v.Check(seller.Slug != "me", "slug", "is reserved")
+
+	v.Check(len(seller.Bio) <= 2000, "bio", "must not exceed 2000 characters")
+
+	if seller.LogoURL != "" {
+		v.Check(len(seller.LogoURL) <= 1000, "logo_url", "must not exceed 1000 characters")
+		v.Check(validator.IsURL(seller.LogoURL), "logo_url", "must be a valid URL")
+	}
+
+	v.Check(len(seller.ReturnPolicy) <= 5000, "return_policy", "must not exceed 5000 characters")
+}
+
+type SellerModel struct {
+	DB *sql.DB
+}
+
+// sellerColumns selects a seller's profile and the number of their visible products.
+const sellerColumns = `
+        s.user_id, s.slug, s.display_name, s.bio, s.logo_url, s.return_policy,
+        (SELECT count(*) FROM products WHERE products.user_id = s.user_id AND ` + productVisible + `),
+        s.created_at, s.updated_at, s.version`
+
+// Insert adds a profile. It returns ErrDuplicateSlug if the slug is taken.
+func (m SellerModel) Insert(seller *Seller) error {
+	query := `
+        INSERT INTO seller_profiles (user_id, slug, display_name, bio, logo_url, return_policy)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING created_at, updated_at, version`
+
+	args := []interface{}{
+		seller.UserID,
+		seller.Slug,
+		seller.DisplayName,
+		seller.Bio,
+		seller.LogoURL,
+		seller.ReturnPolicy,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&seller.CreatedAt, &seller.UpdatedAt, &seller.Version)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateSlug
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBySlug fetches the storefront with the given slug.
+func (m SellerModel) GetBySlug(slug string) (*Seller, error) {
+	return m.get(`s.slug = $1`, slug)
+}
+
+// GetForUser fetches the profile belonging to a user.
+func (m SellerModel) GetForUser(userID int64) (*Seller, error) {
+	return m.get(`s.user_id = $1`, userID)
+}
+
+func (m SellerModel) get(condition string, arg interface{}) (*Seller, error) {
+	query := `
+        SELECT ` + sellerColumns + `
+        FROM seller_profiles s
+        WHERE ` + condition
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var seller Seller
+
+	err := m.DB.QueryRowContext(ctx, query, arg).Scan(
+		&seller.UserID,
+		&seller.Slug,
+		&seller.DisplayName,
+		&seller.Bio,
+		&seller.LogoURL,
+		&seller.ReturnPolicy,
+		&seller.ProductCount,
+		&seller.CreatedAt,
+		&seller.UpdatedAt,
+		&seller.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &seller, nil
+}
+
+// Update saves a profile. It returns ErrDuplicateSlug if the new slug is taken.
+func (m SellerModel) Update(seller *Seller) error {
+	query := `
+        UPDATE seller_profiles
+        SET slug = $1, display_name = $2, bio = $3, logo_url = $4, return_policy = $5,
+            updated_at = NOW(), version = version + 1
+        WHERE user_id = $6 AND version = $7
+        RETURNING updated_at, version`
+
+	args := []interface{}{
+		seller.Slug,
+		seller.DisplayName,
+		seller.Bio,
+		seller.LogoURL,
+		seller.ReturnPolicy,
+		seller.UserID,
+		seller.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&seller.UpdatedAt, &seller.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isUniqueViolation(err):
+			return ErrDuplicateSlug
+		default:
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// Reasons for a stock movement. StockInitial is only recorded for the opening stock
+// of a new product or variant.
+const (
+	StockInitial    = "initial"
+	StockSale       = "sale"
+	StockRestock    = "restock"
+	StockAdjustment = "adjustment"
+	StockReturn     = "return"
+)
+
+// StockReasons are the reasons a seller can give when recording a movement.
+var StockReasons = []string{StockSale, StockRestock, StockAdjustment, StockReturn}
+
+// StockMovement is an entry in the inventory ledger. Delta is added to the stock of
+// the product, or of the variant if VariantID is set, and Balance is the stock left
+// afterwards.
+type StockMovement struct {
+	ID          int64     `json:"id"`
+	ProductID   int64     `json:"product_id"`
+	VariantID   *int64    `json:"variant_id,omitempty"`
+	Delta       int32     `json:"delta"`
+	Reason      string    `json:"reason"`
+	ReferenceID string    `json:"reference_id,omitempty"`
+	ActorID     *int64    `json:"actor_id,omitempty"`
+	Balance     int32     `json:"balance"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// StockReconciliation compares the stock of a product, or one of its variants, with
+// the sum of its ledger entries.
+type StockReconciliation struct {
+	VariantID   *int64 `json:"variant_id,omitempty"`
+	Stock       int32  `json:"stock"`
+	LedgerTotal int64  `json:"ledger_total"`
+	Balanced    bool   `json:"balanced"`
+}
+
+func ValidateStockMovement(v *validator.Validator, movement *StockMovement) {
+	v.Check(movement.Delta != 0, "delta", "must not be zero")
+	v.Check(movement.Delta > -1000000 && movement.Delta < 1000000, "delta", "must be less than 1,000,000 either way")
+
+	v.Check(validator.In(movement.Reason, StockReasons...), "reason", "must be one of sale, restock, adjustment or return")
+	switch movement.Reason {
+	case StockSale:
+		v.Check(movement.Delta < 0, "delta", "must be negative for a sale")
+	case StockRestock, StockReturn:
+		v.Check(movement.Delta > 0, "delta", "must be positive for a "+movement.Reason)
+	}
+
+	v.Check(len(movement.ReferenceID) <= 100, "reference_id", "must not exceed 100 characters")
+
+	if movement.VariantID != nil {
+		v.Check(*movement.VariantID > 0, "variant_id", "must be a positive integer")
+	}
+}
+
+type StockModel struct {
+	DB *sql.DB
+}
+
+// Record applies a movement to the stock of its product or variant and adds it to the
+// ledger, in one transaction. It returns ErrInsufficientStock if the stock would go
+// below zero, and ErrRecordNotFound if the variant doesn't belong to the product.
+func (m StockModel) Record(movement *StockMovement) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var query string
+	var args []interface{}
+	if movement.VariantID == nil {
+		query = `
+            UPDATE products
+            SET stock = stock + $2, updated_at = NOW(), version = version + 1
+            WHERE id = $1 AND stock + $2 >= 0
+            RETURNING stock`
+		args = []interface{}{movement.ProductID, movement.Delta}
+	} else {
+		query = `
+            UPDATE product_variants
+            SET stock = stock + $3, updated_at = NOW(), version = version + 1
+            WHERE id = $1 AND product_id = $2 AND stock + $3 >= 0
+            RETURNING stock`
+		args = []interface{}{*movement.VariantID, movement.ProductID, movement.Delta}
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&movement.Balance)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// Work out whether it doesn't exist or hasn't got enough stock.
+		var exists bool
+		if movement.VariantID == nil {
+			err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`,
+				movement.ProductID).Scan(&exists)
+		} else {
+			err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM product_variants WHERE id = $1 AND product_id = $2)`,
+				*movement.VariantID, movement.ProductID).Scan(&exists)
+		}
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrRecordNotFound
+		}
+		return ErrInsufficientStock
+	}
+
+	if err = insertStockMovementTx(ctx, tx, movement); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAllForProduct returns a page of a product's ledger, newest first. A non-nil
+// variantID limits it to that variant's movements, and 0 to the product's own.
+func (m StockModel) GetAllForProduct(productID int64, variantID *int64, reason string, filters Filters) ([]*StockMovement, Metadata, error) {
+	query := `
+        SELECT count(*) OVER(), id, product_id, variant_id, delta, reason, reference_id, actor_id, balance, created_at
+        FROM stock_movements
+        WHERE product_id = $1
+        AND ($2::bigint IS NULL OR ($2 = 0 AND variant_id IS NULL) OR variant_id = $2)
+        AND ($3 = '' OR reason = $3)
+        ORDER BY created_at DESC, id DESC
+        LIMIT $4 OFFSET $5`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, productID, variantID, reason, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	movements := []*StockMovement{}
+
+	for rows.Next() {
+		var movement StockMovement
+		err := rows.Scan(
+			&totalRecords,
+			&movement.ID,
+			&movement.ProductID,
+			&movement.VariantID,
+			&movement.Delta,
+			&movement.Reason,
+			&movement.ReferenceID,
+			&movement.ActorID,
+			&movement.Balance,
+			&movement.CreatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movements = append(movements, &movement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movements, metadata, nil
+}
+
+// Reconcile compares the stock of a product and each of its variants with the sum of
+// their ledger entries. Anything not Balanced was changed without going through the
+// ledger.
+func (m StockModel) Reconcile(productID int64) ([]*StockReconciliation, error) {
+	query := `
+        SELECT NULL::bigint, p.stock,
+               COALESCE((SELECT sum(delta) FROM stock_movements s
+                         WHERE s.product_id = p.id AND s.variant_id IS NULL), 0)
+        FROM products p
+        WHERE p.id = $1
+        UNION ALL
+        (SELECT v.id, v.stock,
+                COALESCE((SELECT sum(delta) FROM stock_movements s WHERE s.variant_id = v.id), 0)
+         FROM product_variants v
+         WHERE v.product_id = $1
+         ORDER BY v.id)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*StockReconciliation{}
+
+	for rows.Next() {
+		var result StockReconciliation
+		if err := rows.Scan(&result.VariantID, &result.Stock, &result.LedgerTotal); err != nil {
+			return nil, err
+		}
+		result.Balanced = int64(result.Stock) == result.LedgerTotal
+		results = append(results, &result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return results, nil
+}
+
+// insertStockMovementTx adds a movement, whose Balance has already been worked out,
+// to the ledger and queues any notifications it causes. It is used by the models that
+// change stock within their own transactions.
+func insertStockMovementTx(ctx context.Context, tx *sql.Tx, movement *StockMovement) error {
+	query := `
+        INSERT INTO stock_movements (product_id, variant_id, delta, reason, reference_id, actor_id, balance)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at`
+
+	args := []interface{}{
+		movement.ProductID,
+		movement.VariantID,
+		movement.Delta,
+		movement.Reason,
+		movement.ReferenceID,
+		movement.ActorID,
+		movement.Balance,
+	}
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&movement.ID, &movement.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return queueStockNotificationsTx(ctx, tx, movement)
+}
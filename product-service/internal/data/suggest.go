@@ -0,0 +1,88 @@
+package data
+
+import (
+	"context"
+	"strings"
+)
+
+// Suggestion is an autocomplete match for a partially typed search.
+type Suggestion struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id"`
+	Slug string `json:"slug,omitempty"`
+	Text string `json:"text"`
+}
+
+// likeEscaper escapes the LIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Suggest returns product names and categories that start with q or resemble it
+// closely enough to be a misspelling. Prefix matches rank first, then the closest
+// fuzzy matches. Unlike the other model methods it takes a context, so the caller can
+// hold it to a tight deadline.
+func (m ProductModel) Suggest(ctx context.Context, q string, limit int) ([]Suggestion, error) {
+	q = strings.ToLower(strings.TrimSpace(q))
+	prefix := likeEscaper.Replace(q) + "%"
+
+	query := `
+        (SELECT 'category', id, slug, name
+         FROM categories
+         WHERE lower(name) LIKE $2 OR $1 <% lower(name)
+         ORDER BY lower(name) LIKE $2 DESC, word_similarity($1, lower(name)) DESC, name
+         LIMIT $3)
+        UNION ALL
+        (SELECT 'product', id, '', name
+         FROM products
+         WHERE (lower(name) LIKE $2 OR $1 <% lower(name)) AND ` + productVisible + `
+         ORDER BY lower(name) LIKE $2 DESC, word_similarity($1, lower(name)) DESC, name
+         LIMIT $3)`
+
+	rows, err := m.DB.QueryContext(ctx, query, q, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []Suggestion{}
+
+	for rows.Next() {
+		var s Suggestion
+		if err := rows.Scan(&s.Type, &s.ID, &s.Slug, &s.Text); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Categories come first but shouldn't crowd out the products entirely: they get at
+	// most half the slots when there are enough products for the rest, and otherwise
+	// whatever the products leave free.
+	if len(suggestions) > limit {
+		categories := 0
+		for _, s := range suggestions {
+			if s.Type == "category" {
+				categories++
+			}
+		}
+		products := len(suggestions) - categories
+		keep := min(categories, max(limit/2, limit-products))
+		trimmed := make([]Suggestion, 0, limit)
+		for _, s := range suggestions {
+			if s.Type == "category" {
+				if keep == 0 {
+					continue
+				}
+				keep--
+			}
+			if len(trimmed) < limit {
+				trimmed = append(trimmed, s)
+			}
+		}
+		suggestions = trimmed
+	}
+
+	return suggestions, nil
+}
@@ -2,10 +2,24 @@ package data
 
 // Minimal User struct - just what we need from user-service
 type User struct {
-	ID        int64  `json:"id"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	Activated bool   `json:"activated"`
+	ID          int64       `json:"id"`
+	Email       string      `json:"email"`
+	Name        string      `json:"name"`
+	Activated   bool        `json:"activated"`
+	Permissions Permissions `json:"permissions"`
+}
+
+// Permissions holds the permission codes granted to a user, e.g. "categories:admin".
+type Permissions []string
+
+// Include checks whether the Permissions slice contains a specific permission code.
+func (p Permissions) Include(code string) bool {
+	for i := range p {
+		if code == p[i] {
+			return true
+		}
+	}
+	return false
 }
 
 // AnonymousUser represents an unauthenticated user
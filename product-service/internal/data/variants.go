@@ -0,0 +1,349 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+	"github.com/lib/pq"
+)
+
+var ErrDuplicateSKU = errors.New("duplicate sku")
+
+var skuRX = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ProductVariant is a purchasable version of a product, such as a particular size and
+// colour, with its own SKU and stock. Price is optional and overrides the product's
+// price when set.
+type ProductVariant struct {
+	ID        int64             `json:"id"`
+	ProductID int64             `json:"product_id"`
+	SKU       string            `json:"sku"`
+	Options   map[string]string `json:"options"`
+	Price     *Price            `json:"price,omitempty"`
+	Stock     int32             `json:"stock"`
+	ImageUrl  *string           `json:"image_url,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Version   int32             `json:"version"`
+}
+
+// NormalizeOptions lower-cases option names so that "Size" and "size" are the same
+// option, and spells "color" as "colour" so the list filters find it either way.
+func NormalizeOptions(options map[string]string) map[string]string {
+	normalized := make(map[string]string, len(options))
+	for name, value := range options {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "color" {
+			name = "colour"
+		}
+		normalized[name] = strings.TrimSpace(value)
+	}
+	return normalized
+}
+
+func ValidateVariant(v *validator.Validator, variant *ProductVariant) {
+	v.Check(variant.SKU != "", "sku", "must be provided")
+	v.Check(len(variant.SKU) <= 64, "sku", "must not exceed 64 characters")
+	v.Check(validator.Matches(variant.SKU, skuRX), "sku", "must contain only letters, digits, '.', '_' or '-'")
+
+	v.Check(len(variant.Options) > 0, "options", "must have at least one option")
+	v.Check(len(variant.Options) <= 10, "options", "must not exceed 10 options")
+	for name, value := range variant.Options {
+		v.Check(name != "" && len(name) <= 50, "options", "option names must be between 1 and 50 characters")
+		v.Check(value != "" && len(value) <= 100, "options."+name, "must be between 1 and 100 characters")
+	}
+
+	if variant.Price != nil {
+		v.Check(*variant.Price > 0, "price", "must be greater than zero")
+		v.Check(*variant.Price < 1000000, "price", "must be less than 1,000,000")
+	}
+
+	v.Check(variant.Stock >= 0, "stock", "must be zero or greater")
+
+	if variant.ImageUrl != nil {
+		v.Check(*variant.ImageUrl != "", "image_url", "must not be empty if provided")
+		v.Check(len(*variant.ImageUrl) <= 1000, "image_url", "must not exceed 1000 characters")
+	}
+}
+
+type VariantModel struct {
+	DB *sql.DB
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// Insert adds a variant, recording its opening stock in the ledger as added by
+// actorID.
+func (m VariantModel) Insert(variant *ProductVariant, actorID int64) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO product_variants (product_id, sku, options, price, stock, image_url)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at, updated_at, version`
+
+	args := []interface{}{
+		variant.ProductID,
+		variant.SKU,
+		options,
+		variant.Price,
+		variant.Stock,
+		variant.ImageUrl,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&variant.ID,
+		&variant.CreatedAt,
+		&variant.UpdatedAt,
+		&variant.Version,
+	)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateSKU
+		default:
+			return err
+		}
+	}
+
+	if variant.Stock != 0 {
+		err = insertStockMovementTx(ctx, tx, &StockMovement{
+			ProductID: variant.ProductID,
+			VariantID: &variant.ID,
+			Delta:     variant.Stock,
+			Reason:    StockInitial,
+			ActorID:   &actorID,
+			Balance:   variant.Stock,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get fetches a variant, making sure it belongs to the given product.
+func (m VariantModel) Get(id, productID int64) (*ProductVariant, error) {
+	if id < 1 || productID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT id, product_id, sku, options, price, stock, image_url, created_at, updated_at, version
+        FROM product_variants
+        WHERE id = $1 AND product_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	variant, err := scanVariant(m.DB.QueryRowContext(ctx, query, id, productID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return variant, nil
+}
+
+func (m VariantModel) GetAllForProduct(productID int64) ([]*ProductVariant, error) {
+	query := `
+        SELECT id, product_id, sku, options, price, stock, image_url, created_at, updated_at, version
+        FROM product_variants
+        WHERE product_id = $1
+        ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []*ProductVariant{}
+
+	for rows.Next() {
+		variant, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// Update saves a variant. Like ProductModel.Update, a change of stock is recorded in
+// the ledger as an adjustment by actorID.
+func (m VariantModel) Update(variant *ProductVariant, actorID int64) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var stock int32
+	err = tx.QueryRowContext(ctx, `SELECT stock FROM product_variants WHERE id = $1 AND version = $2 FOR UPDATE`,
+		variant.ID, variant.Version).Scan(&stock)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	query := `
+        UPDATE product_variants
+        SET sku = $1, options = $2, price = $3, stock = $4, image_url = $5,
+            updated_at = NOW(), version = version + 1
+        WHERE id = $6 AND version = $7
+        RETURNING version, updated_at`
+
+	args := []interface{}{
+		variant.SKU,
+		options,
+		variant.Price,
+		variant.Stock,
+		variant.ImageUrl,
+		variant.ID,
+		variant.Version,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&variant.Version, &variant.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isUniqueViolation(err):
+			return ErrDuplicateSKU
+		default:
+			return err
+		}
+	}
+
+	if delta := variant.Stock - stock; delta != 0 {
+		err = insertStockMovementTx(ctx, tx, &StockMovement{
+			ProductID: variant.ProductID,
+			VariantID: &variant.ID,
+			Delta:     delta,
+			Reason:    StockAdjustment,
+			ActorID:   &actorID,
+			Balance:   variant.Stock,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m VariantModel) Delete(id, productID int64) error {
+	if id < 1 || productID < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM product_variants
+        WHERE id = $1 AND product_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, productID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVariant(row rowScanner) (*ProductVariant, error) {
+	var variant ProductVariant
+	var options []byte
+	var price sql.NullFloat64
+
+	err := row.Scan(
+		&variant.ID,
+		&variant.ProductID,
+		&variant.SKU,
+		&options,
+		&price,
+		&variant.Stock,
+		&variant.ImageUrl,
+		&variant.CreatedAt,
+		&variant.UpdatedAt,
+		&variant.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if price.Valid {
+		p := Price(price.Float64)
+		variant.Price = &p
+	}
+
+	if err := json.Unmarshal(options, &variant.Options); err != nil {
+		return nil, fmt.Errorf("decode variant options: %w", err)
+	}
+
+	return &variant, nil
+}
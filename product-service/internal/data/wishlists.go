@@ -0,0 +1,479 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/validator"
+)
+
+var (
+	ErrDuplicateWishlist     = errors.New("duplicate wishlist name")
+	ErrDuplicateWishlistItem = errors.New("duplicate wishlist item")
+	ErrTooManyWishlistItems  = errors.New("too many wishlist items")
+)
+
+// MaxWishlistItems is the most products a single wishlist can hold.
+const MaxWishlistItems = 200
+
+// Wishlist is a named list of products a user has saved for later. ShareToken is set
+// while the list is shared publicly.
+type Wishlist struct {
+	ID         int64           `json:"id"`
+	UserID     int64           `json:"user_id"`
+	Name       string          `json:"name"`
+	ShareToken *string         `json:"share_token,omitempty"`
+	ItemCount  int             `json:"item_count"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	Version    int32           `json:"version"`
+	Items      []*WishlistItem `json:"items,omitempty"`
+}
+
+// WishlistItem is a product saved to a wishlist. SavedPrice is the product's price when
+// it was saved; CurrentPrice and PriceChange are left nil once the product has been
+// deleted or archived, which ProductDeleted flags.
+type WishlistItem struct {
+	ID             int64     `json:"id"`
+	WishlistID     int64     `json:"wishlist_id"`
+	ProductID      *int64    `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	ImageUrl       string    `json:"image_url,omitempty"`
+	Note           string    `json:"note"`
+	SavedPrice     Price     `json:"saved_price"`
+	CurrentPrice   *Price    `json:"current_price,omitempty"`
+	PriceChange    *Price    `json:"price_change,omitempty"`
+	InStock        bool      `json:"in_stock"`
+	ProductDeleted bool      `json:"product_deleted"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func ValidateWishlist(v *validator.Validator, wishlist *Wishlist) {
+	v.Check(wishlist.Name != "", "name", "must be provided")
+	v.Check(len(wishlist.Name) <= 100, "name", "must not exceed 100 characters")
+}
+
+func ValidateWishlistNote(v *validator.Validator, note string) {
+	v.Check(len(note) <= 500, "note", "must not exceed 500 characters")
+}
+
+type WishlistModel struct {
+	DB *sql.DB
+}
+
+func (m WishlistModel) Insert(wishlist *Wishlist) error {
+	query := `
+        INSERT INTO wishlists (user_id, name, share_token)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at, updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, wishlist.UserID, wishlist.Name, wishlist.ShareToken).Scan(
+		&wishlist.ID,
+		&wishlist.CreatedAt,
+		&wishlist.UpdatedAt,
+		&wishlist.Version,
+	)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicateWishlist
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Get fetches one of the user's wishlists, without its items.
+func (m WishlistModel) Get(id, userID int64) (*Wishlist, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT w.id, w.user_id, w.name, w.share_token,
+               (SELECT count(*) FROM wishlist_items wi WHERE wi.wishlist_id = w.id),
+               w.created_at, w.updated_at, w.version
+        FROM wishlists w
+        WHERE w.id = $1 AND w.user_id = $2`
+
+	return m.getOne(query, id, userID)
+}
+
+// GetByShareToken fetches a shared wishlist, without its items.
+func (m WishlistModel) GetByShareToken(token string) (*Wishlist, error) {
+	query := `
+        SELECT w.id, w.user_id, w.name, w.share_token,
+               (SELECT count(*) FROM wishlist_items wi WHERE wi.wishlist_id = w.id),
+               w.created_at, w.updated_at, w.version
+        FROM wishlists w
+        WHERE w.share_token = $1`
+
+	return m.getOne(query, token)
+}
+
+func (m WishlistModel) getOne(query string, args ...interface{}) (*Wishlist, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var wishlist Wishlist
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&wishlist.ID,
+		&wishlist.UserID,
+		&wishlist.Name,
+		&wishlist.ShareToken,
+		&wishlist.ItemCount,
+		&wishlist.CreatedAt,
+		&wishlist.UpdatedAt,
+		&wishlist.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &wishlist, nil
+}
+
+// GetAllForUser returns the user's wishlists, without their items.
+func (m WishlistModel) GetAllForUser(userID int64) ([]*Wishlist, error) {
+	query := `
+        SELECT w.id, w.user_id, w.name, w.share_token,
+               (SELECT count(*) FROM wishlist_items wi WHERE wi.wishlist_id = w.id),
+               w.created_at, w.updated_at, w.version
+        FROM wishlists w
+        WHERE w.user_id = $1
+        ORDER BY w.created_at, w.id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishlists := []*Wishlist{}
+
+	for rows.Next() {
+		var wishlist Wishlist
+		err := rows.Scan(
+			&wishlist.ID,
+			&wishlist.UserID,
+			&wishlist.Name,
+			&wishlist.ShareToken,
+			&wishlist.ItemCount,
+			&wishlist.CreatedAt,
+			&wishlist.UpdatedAt,
+			&wishlist.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, &wishlist)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return wishlists, nil
+}
+
+func (m WishlistModel) Update(wishlist *Wishlist) error {
+	query := `
+        UPDATE wishlists
+        SET name = $1, share_token = $2, updated_at = NOW(), version = version + 1
+        WHERE id = $3 AND version = $4
+        RETURNING updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, wishlist.Name, wishlist.ShareToken, wishlist.ID, wishlist.Version).Scan(
+		&wishlist.UpdatedAt,
+		&wishlist.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isUniqueViolation(err):
+			return ErrDuplicateWishlist
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (m WishlistModel) Delete(id, userID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM wishlists
+        WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// wishlistItemColumns selects a wishlist item along with the current state of its
+// product, which is missing once the product has been deleted.
+const wishlistItemColumns = `
+        wi.id, wi.wishlist_id, wi.product_id, wi.product_name, wi.note, wi.saved_price, wi.created_at,
+        COALESCE(p.status = 'archived', false), ` + productEffectivePrice + `, COALESCE(p.image_url, ''),
+        COALESCE(p.stock > 0 OR EXISTS (
+            SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.stock > 0
+        ), false)`
+
+// GetItems returns a wishlist's items, most recently saved first.
+func (m WishlistModel) GetItems(wishlistID int64) ([]*WishlistItem, error) {
+	query := `
+        SELECT ` + wishlistItemColumns + `
+        FROM wishlist_items wi
+        LEFT JOIN products p ON p.id = wi.product_id
+        WHERE wi.wishlist_id = $1
+        ORDER BY wi.created_at DESC, wi.id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*WishlistItem{}
+
+	for rows.Next() {
+		item, err := scanWishlistItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// GetItem fetches an item, making sure it belongs to the given wishlist.
+func (m WishlistModel) GetItem(id, wishlistID int64) (*WishlistItem, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+        SELECT ` + wishlistItemColumns + `
+        FROM wishlist_items wi
+        LEFT JOIN products p ON p.id = wi.product_id
+        WHERE wi.id = $1 AND wi.wishlist_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	item, err := scanWishlistItem(m.DB.QueryRowContext(ctx, query, id, wishlistID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return item, nil
+}
+
+// AddItem saves a product to a wishlist, recording its current name and price. It
+// returns ErrRecordNotFound if the product doesn't exist.
+func (m WishlistModel) AddItem(wishlistID, productID int64, note string) (*WishlistItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the wishlist so that concurrent adds can't exceed the limit.
+	var count int
+	err = tx.QueryRowContext(ctx, `
+        SELECT (SELECT count(*) FROM wishlist_items WHERE wishlist_id = w.id)
+        FROM wishlists w
+        WHERE w.id = $1
+        FOR UPDATE`, wishlistID).Scan(&count)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if count >= MaxWishlistItems {
+		return nil, ErrTooManyWishlistItems
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO wishlist_items (wishlist_id, product_id, product_name, saved_price, note)
+        SELECT $1, p.id, p.name, `+productEffectivePrice+`, $3
+        FROM products p
+        WHERE p.id = $2 AND `+productVisible+`
+        RETURNING id`, wishlistID, productID, note).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		case isUniqueViolation(err):
+			return nil, ErrDuplicateWishlistItem
+		default:
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE wishlists SET updated_at = NOW() WHERE id = $1`, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := scanWishlistItem(tx.QueryRowContext(ctx, `
+        SELECT `+wishlistItemColumns+`
+        FROM wishlist_items wi
+        LEFT JOIN products p ON p.id = wi.product_id
+        WHERE wi.id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	return item, tx.Commit()
+}
+
+func (m WishlistModel) UpdateItemNote(item *WishlistItem) error {
+	query := `
+        UPDATE wishlist_items
+        SET note = $1
+        WHERE id = $2 AND wishlist_id = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, item.Note, item.ID, item.WishlistID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m WishlistModel) DeleteItem(id, wishlistID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+        DELETE FROM wishlist_items
+        WHERE id = $1 AND wishlist_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, wishlistID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func scanWishlistItem(row rowScanner) (*WishlistItem, error) {
+	var item WishlistItem
+	var archived bool
+	var currentPrice sql.NullFloat64
+
+	err := row.Scan(
+		&item.ID,
+		&item.WishlistID,
+		&item.ProductID,
+		&item.ProductName,
+		&item.Note,
+		&item.SavedPrice,
+		&item.CreatedAt,
+		&archived,
+		&currentPrice,
+		&item.ImageUrl,
+		&item.InStock,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.ProductID == nil || archived {
+		item.ProductDeleted = true
+		return &item, nil
+	}
+
+	if currentPrice.Valid {
+		price := Price(currentPrice.Float64)
+		change := Price(math.Round(float64(price-item.SavedPrice)*100) / 100)
+		item.CurrentPrice = &price
+		item.PriceChange = &change
+	}
+
+	return &item, nil
+}
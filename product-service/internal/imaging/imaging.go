@@ -0,0 +1,118 @@
+// internal/imaging/imaging.go
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrTooManyPixels   = errors.New("image dimensions too large")
+)
+
+// MaxPixels limits the decoded size of an image, so that a small, highly compressed
+// upload can't exhaust memory.
+const MaxPixels = 40_000_000
+
+// SupportedTypes are the content types that can be decoded, keyed to the file
+// extension used when storing them.
+var SupportedTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// Sniff detects the content type of an image from its first bytes, ignoring whatever
+// the client claimed. It returns ErrUnsupportedType for anything that isn't a
+// supported image.
+func Sniff(head []byte) (string, error) {
+	contentType := http.DetectContentType(head)
+	if _, ok := SupportedTypes[contentType]; !ok {
+		return "", ErrUnsupportedType
+	}
+	return contentType, nil
+}
+
+// Decode decodes an image after checking that its dimensions are within MaxPixels.
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	return img, nil
+}
+
+// Fit scales img down so that neither side exceeds maxSide, keeping its aspect ratio.
+// Images that already fit are returned unchanged. Each destination pixel is the
+// average of the source pixels it covers, which avoids the aliasing of nearest-
+// neighbour scaling.
+func Fit(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+
+	dw, dh := maxSide, maxSide
+	if w > h {
+		dh = max(1, h*maxSide/w)
+	} else {
+		dw = max(1, w*maxSide/h)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					bl += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// Encode writes img in the format of contentType. GIFs are re-encoded as PNG since
+// renditions are single frames; the returned content type says what was written.
+func Encode(w io.Writer, img image.Image, contentType string) (string, error) {
+	switch contentType {
+	case "image/jpeg":
+		return contentType, jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "image/png", "image/gif":
+		return "image/png", png.Encode(w, img)
+	default:
+		return "", ErrUnsupportedType
+	}
+}
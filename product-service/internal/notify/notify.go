@@ -0,0 +1,124 @@
+// Package notify delivers notifications to users. The Notifier interface lets the
+// delivery channel be chosen at startup: the log, a webhook or email through
+// user-service.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/PaulBabatuyi/FashionMarket-Backend/product-service/internal/jsonlog"
+)
+
+// Message is a notification for a single user.
+type Message struct {
+	ID      int64           `json:"id"`
+	UserID  int64           `json:"user_id"`
+	Kind    string          `json:"kind"`
+	Subject string          `json:"subject"`
+	Body    string          `json:"body"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Notifier sends a message. An error means the message should be tried again later.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Log writes messages to the application log. It is meant for development.
+type Log struct {
+	Logger *jsonlog.Logger
+}
+
+func (l Log) Notify(ctx context.Context, msg Message) error {
+	l.Logger.PrintInfo("notification", map[string]string{
+		"id":      strconv.FormatInt(msg.ID, 10),
+		"user_id": strconv.FormatInt(msg.UserID, 10),
+		"kind":    msg.Kind,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	return nil
+}
+
+// Webhook POSTs each message as JSON to URL. If Secret is set the body is signed with
+// HMAC-SHA256 and the hex digest sent in the X-Signature-SHA256 header. The message ID
+// is sent in X-Notification-ID so that receivers can ignore retried deliveries.
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func (wh Webhook) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Notification-ID", strconv.FormatInt(msg.ID, 10))
+	if wh.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(wh.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-SHA256", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	return send(wh.Client, req, "webhook")
+}
+
+// Email asks user-service to email the message to the user, so that this service
+// never needs to know users' addresses. Token authenticates a user-service account
+// holding the notifications:send permission.
+type Email struct {
+	UserServiceURL string
+	Token          string
+	Client         *http.Client
+}
+
+func (e Email) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]string{
+		"subject": msg.Subject,
+		"message": msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v1/users/%d/notifications", e.UserServiceURL, msg.UserID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.Token)
+
+	return send(e.Client, req, "user-service")
+}
+
+func send(client *http.Client, req *http.Request, name string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", name, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned status %d", name, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS product_variants (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL,
+    options JSONB NOT NULL DEFAULT '{}',
+    price DECIMAL(10, 2) CHECK (price >= 0),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    image_url TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    -- Scoped to the product, so that sellers can't take each other's SKUs.
+    UNIQUE (product_id, sku)
+);
+
+CREATE INDEX idx_product_variants_product_id ON product_variants(product_id);
+-- Option values are matched case-insensitively by the list filters.
+CREATE INDEX idx_product_variants_size ON product_variants(lower(options->>'size'));
+CREATE INDEX idx_product_variants_colour ON product_variants(lower(options->>'colour'));
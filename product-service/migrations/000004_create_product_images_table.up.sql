@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    url TEXT NOT NULL,
+    medium_url TEXT NOT NULL,
+    thumbnail_url TEXT NOT NULL,
+    storage_keys TEXT[] NOT NULL DEFAULT '{}',
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_product_images_product_id ON product_images(product_id, position);
+-- A product has at most one primary image.
+CREATE UNIQUE INDEX idx_product_images_primary ON product_images(product_id) WHERE is_primary;
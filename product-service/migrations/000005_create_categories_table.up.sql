@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id BIGSERIAL PRIMARY KEY,
+    parent_id BIGINT REFERENCES categories(id) ON DELETE RESTRICT,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (parent_id <> id)
+);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+
+-- Seed the taxonomy with the categories products already use, as top-level
+-- categories, and store products' categories as slugs from now on.
+INSERT INTO categories (name, slug)
+SELECT DISTINCT ON (slug) initcap(trim(value)), slug
+FROM (
+    SELECT c AS value, trim(BOTH '-' FROM regexp_replace(lower(c), '[^a-z0-9]+', '-', 'g')) AS slug
+    FROM products, unnest(category) AS c
+) AS existing
+WHERE slug <> ''
+ORDER BY slug, value
+ON CONFLICT (slug) DO NOTHING;
+
+UPDATE products SET category = (
+    SELECT coalesce(array_agg(DISTINCT s), '{}')
+    FROM (
+        SELECT trim(BOTH '-' FROM regexp_replace(lower(c), '[^a-z0-9]+', '-', 'g')) AS s
+        FROM unnest(products.category) AS c
+    ) AS slugs
+    WHERE s <> ''
+);
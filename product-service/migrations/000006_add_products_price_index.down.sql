@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_product_variants_in_stock;
+DROP INDEX IF EXISTS idx_products_price;
@@ -0,0 +1,4 @@
+-- Supports the price range filter and the price facet.
+CREATE INDEX IF NOT EXISTS idx_products_price ON products(price);
+-- Supports the in_stock filter and facet.
+CREATE INDEX IF NOT EXISTS idx_product_variants_in_stock ON product_variants(product_id) WHERE stock > 0;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS product_reviews_refresh_rating ON product_reviews;
+DROP FUNCTION IF EXISTS refresh_product_rating();
+DROP INDEX IF EXISTS idx_products_rating_average;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_count, DROP COLUMN IF EXISTS rating_average;
+DROP TABLE IF EXISTS review_reports;
+DROP TABLE IF EXISTS product_reviews;
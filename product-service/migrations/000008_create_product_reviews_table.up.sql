@@ -0,0 +1,63 @@
+CREATE TABLE IF NOT EXISTS product_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    -- The delivered order that made the reviewer a verified buyer.
+    order_id BIGINT NOT NULL,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    title VARCHAR(150) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    photo_urls TEXT[] NOT NULL DEFAULT '{}',
+    seller_reply TEXT,
+    seller_replied_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX idx_product_reviews_product_id ON product_reviews(product_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS review_reports (
+    id BIGSERIAL PRIMARY KEY,
+    review_id BIGINT NOT NULL REFERENCES product_reviews(id) ON DELETE CASCADE,
+    reporter_id BIGINT NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (review_id, reporter_id)
+);
+
+-- The aggregates are kept on the product so that lists can show and sort by them
+-- without touching the reviews.
+ALTER TABLE products
+    ADD COLUMN rating_average DECIMAL(3, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN rating_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_products_rating_average ON products(rating_average);
+
+CREATE OR REPLACE FUNCTION refresh_product_rating() RETURNS TRIGGER AS $$
+DECLARE
+    pid BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        pid := OLD.product_id;
+    ELSE
+        pid := NEW.product_id;
+    END IF;
+
+    UPDATE products p
+    SET rating_average = COALESCE(r.average, 0), rating_count = r.count
+    FROM (
+        SELECT round(avg(rating), 2) AS average, count(*) AS count
+        FROM product_reviews
+        WHERE product_id = pid
+    ) r
+    WHERE p.id = pid;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_reviews_refresh_rating
+AFTER INSERT OR DELETE OR UPDATE OF rating ON product_reviews
+FOR EACH ROW EXECUTE FUNCTION refresh_product_rating();
@@ -0,0 +1,25 @@
+-- Every change to products.stock or product_variants.stock is recorded here, in the
+-- same transaction. Movements with no variant_id belong to the product's own stock.
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id BIGINT REFERENCES product_variants(id) ON DELETE CASCADE,
+    delta INTEGER NOT NULL CHECK (delta <> 0),
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('initial', 'sale', 'restock', 'adjustment', 'return')),
+    reference_id VARCHAR(100) NOT NULL DEFAULT '',
+    -- NULL for movements recorded by the system, such as the opening balances below.
+    actor_id BIGINT,
+    -- The stock level after the movement.
+    balance INTEGER NOT NULL CHECK (balance >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stock_movements_product_id ON stock_movements(product_id, created_at DESC);
+CREATE INDEX idx_stock_movements_variant_id ON stock_movements(variant_id) WHERE variant_id IS NOT NULL;
+
+-- Opening balances, so that the ledger reconciles with the stock already on hand.
+INSERT INTO stock_movements (product_id, delta, reason, balance)
+SELECT id, stock, 'initial', stock FROM products WHERE stock <> 0;
+
+INSERT INTO stock_movements (product_id, variant_id, delta, reason, balance)
+SELECT product_id, id, stock, 'initial', stock FROM product_variants WHERE stock <> 0;
@@ -0,0 +1,37 @@
+-- The seller is notified when a stock movement takes the product's stock, or one of
+-- its variants', from above the threshold to at or below it. NULL turns it off.
+ALTER TABLE products ADD COLUMN low_stock_threshold INTEGER CHECK (low_stock_threshold >= 0);
+
+-- "Notify me when back in stock" requests. A subscription without a variant fires when
+-- the product or any of its variants comes back. Each is used once: notified_at is
+-- set when the notification is queued.
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id BIGINT REFERENCES product_variants(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_stock_subscriptions_pending
+ON stock_subscriptions(product_id, COALESCE(variant_id, 0), user_id)
+WHERE notified_at IS NULL;
+
+-- Outbox of notifications waiting to be sent. They are queued in the same transaction
+-- as the stock change that caused them and delivered by a background dispatcher.
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(30) NOT NULL,
+    subject VARCHAR(200) NOT NULL,
+    message TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_pending ON notifications(next_attempt_at) WHERE sent_at IS NULL;
@@ -0,0 +1,16 @@
+-- Products now go through a lifecycle. Existing products are already live, so they
+-- start out published; new ones start as drafts.
+ALTER TABLE products
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft', 'pending_review', 'published', 'archived')),
+    -- A published product isn't visible until publish_at, and stops being visible at
+    -- unpublish_at, when the scheduler archives it.
+    ADD COLUMN publish_at TIMESTAMPTZ,
+    ADD COLUMN unpublish_at TIMESTAMPTZ,
+    ADD CONSTRAINT products_schedule_check CHECK (publish_at IS NULL OR unpublish_at IS NULL OR unpublish_at > publish_at);
+
+ALTER TABLE products ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX idx_products_status ON products(status);
+CREATE INDEX idx_products_publish_at ON products(publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX idx_products_unpublish_at ON products(unpublish_at) WHERE unpublish_at IS NOT NULL;
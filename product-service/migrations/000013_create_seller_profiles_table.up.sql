@@ -0,0 +1,13 @@
+-- A seller's public storefront. Sellers are users from user-service, so the profile
+-- is keyed by their user ID; products find their seller through products.user_id.
+CREATE TABLE IF NOT EXISTS seller_profiles (
+    user_id BIGINT PRIMARY KEY,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    display_name VARCHAR(100) NOT NULL,
+    bio TEXT NOT NULL DEFAULT '',
+    logo_url TEXT NOT NULL DEFAULT '',
+    return_policy TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1
+);
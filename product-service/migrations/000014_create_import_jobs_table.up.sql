@@ -0,0 +1,28 @@
+-- Sellers' own product codes. Bulk imports match rows to existing products by SKU.
+ALTER TABLE products ADD COLUMN sku VARCHAR(100);
+
+CREATE UNIQUE INDEX idx_products_user_id_sku ON products(user_id, sku) WHERE sku IS NOT NULL;
+
+-- Bulk product imports. The uploaded file is kept in payload until the job finishes,
+-- so that jobs interrupted by a restart can be run again.
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'ndjson')),
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'completed', 'failed')),
+    payload BYTEA NOT NULL,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created_rows INTEGER NOT NULL DEFAULT 0,
+    updated_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    -- Per-row validation errors: [{"row": 3, "sku": "...", "errors": {"price": "..."}}]
+    row_errors JSONB NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_import_jobs_user_id ON import_jobs(user_id, created_at DESC);
+CREATE INDEX idx_import_jobs_unfinished ON import_jobs(id) WHERE status IN ('queued', 'running');
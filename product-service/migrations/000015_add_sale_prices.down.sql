@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS price_history;
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_sale_check,
+    DROP COLUMN IF EXISTS sale_ends_at,
+    DROP COLUMN IF EXISTS sale_starts_at,
+    DROP COLUMN IF EXISTS sale_price,
+    DROP COLUMN IF EXISTS compare_at_price;
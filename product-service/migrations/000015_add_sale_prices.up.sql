@@ -0,0 +1,29 @@
+-- compare_at_price is a reference price, such as the RRP, shown struck through.
+-- sale_price replaces price between sale_starts_at and sale_ends_at; either end of
+-- the sale may be left open.
+ALTER TABLE products
+    ADD COLUMN compare_at_price DECIMAL(10, 2) CHECK (compare_at_price > 0),
+    ADD COLUMN sale_price DECIMAL(10, 2) CHECK (sale_price > 0),
+    ADD COLUMN sale_starts_at TIMESTAMPTZ,
+    ADD COLUMN sale_ends_at TIMESTAMPTZ,
+    ADD CONSTRAINT products_sale_check CHECK (sale_starts_at IS NULL OR sale_ends_at IS NULL OR sale_ends_at > sale_starts_at);
+
+-- A row is written whenever any of a product's prices change, holding the new prices.
+CREATE TABLE IF NOT EXISTS price_history (
+    id BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price DECIMAL(10, 2) NOT NULL,
+    compare_at_price DECIMAL(10, 2),
+    sale_price DECIMAL(10, 2),
+    sale_starts_at TIMESTAMPTZ,
+    sale_ends_at TIMESTAMPTZ,
+    -- NULL for the prices recorded below.
+    actor_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_price_history_product_id ON price_history(product_id, created_at DESC);
+
+-- Starting prices, as of when each product was created.
+INSERT INTO price_history (product_id, price, created_at)
+SELECT id, price, created_at FROM products;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS co_purchase_sync;
+DROP TABLE IF EXISTS bought_together;
@@ -0,0 +1,21 @@
+-- How many orders each pair of products was bought together in, according to
+-- order-service. Every pair is stored in both directions.
+CREATE TABLE IF NOT EXISTS bought_together (
+    product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    other_product_id BIGINT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    orders INTEGER NOT NULL CHECK (orders > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, other_product_id)
+);
+
+CREATE INDEX idx_bought_together_ranking ON bought_together(product_id, orders DESC);
+
+-- How far through order-service's order items bought_together has been brought. There
+-- is only ever one row.
+CREATE TABLE IF NOT EXISTS co_purchase_sync (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_order_item_id BIGINT NOT NULL DEFAULT 0,
+    synced_at TIMESTAMPTZ
+);
+
+INSERT INTO co_purchase_sync DEFAULT VALUES;
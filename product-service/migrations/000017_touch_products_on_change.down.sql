@@ -0,0 +1,26 @@
+DROP TRIGGER IF EXISTS product_images_touch_product ON product_images;
+DROP TRIGGER IF EXISTS product_variants_touch_product ON product_variants;
+DROP FUNCTION IF EXISTS touch_product();
+
+CREATE OR REPLACE FUNCTION refresh_product_rating() RETURNS TRIGGER AS $$
+DECLARE
+    pid BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        pid := OLD.product_id;
+    ELSE
+        pid := NEW.product_id;
+    END IF;
+
+    UPDATE products p
+    SET rating_average = COALESCE(r.average, 0), rating_count = r.count
+    FROM (
+        SELECT round(avg(rating), 2) AS average, count(*) AS count
+        FROM product_reviews
+        WHERE product_id = pid
+    ) r
+    WHERE p.id = pid;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
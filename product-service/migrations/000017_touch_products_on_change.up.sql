@@ -0,0 +1,44 @@
+-- Product responses are cached by products.updated_at, so it has to change whenever
+-- anything they show does: the rating, and the product's variants and images.
+CREATE OR REPLACE FUNCTION refresh_product_rating() RETURNS TRIGGER AS $$
+DECLARE
+    pid BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        pid := OLD.product_id;
+    ELSE
+        pid := NEW.product_id;
+    END IF;
+
+    UPDATE products p
+    SET rating_average = COALESCE(r.average, 0), rating_count = r.count, updated_at = NOW()
+    FROM (
+        SELECT round(avg(rating), 2) AS average, count(*) AS count
+        FROM product_reviews
+        WHERE product_id = pid
+    ) r
+    WHERE p.id = pid;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION touch_product() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        UPDATE products SET updated_at = NOW() WHERE id = OLD.product_id;
+    ELSE
+        UPDATE products SET updated_at = NOW() WHERE id = NEW.product_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER product_variants_touch_product
+AFTER INSERT OR UPDATE OR DELETE ON product_variants
+FOR EACH ROW EXECUTE FUNCTION touch_product();
+
+CREATE TRIGGER product_images_touch_product
+AFTER INSERT OR UPDATE OR DELETE ON product_images
+FOR EACH ROW EXECUTE FUNCTION touch_product();
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS categories_notify_change ON categories;
+DROP TRIGGER IF EXISTS products_notify_change ON products;
+DROP FUNCTION IF EXISTS notify_category_change();
+DROP FUNCTION IF EXISTS notify_product_change();
@@ -0,0 +1,31 @@
+-- Each instance caches products and product lists in memory, and listens on
+-- product_changes to drop what another instance, or a trigger, has changed. The
+-- payload is the product's ID, or empty when only the lists are affected.
+CREATE OR REPLACE FUNCTION notify_product_change() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('product_changes', OLD.id::text);
+    ELSE
+        PERFORM pg_notify('product_changes', NEW.id::text);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notify_category_change() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('product_changes', '');
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON products
+FOR EACH ROW EXECUTE FUNCTION notify_product_change();
+
+-- Moving a category changes which products its filter matches.
+CREATE TRIGGER categories_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON categories
+FOR EACH STATEMENT EXECUTE FUNCTION notify_category_change();
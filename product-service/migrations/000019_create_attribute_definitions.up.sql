@@ -0,0 +1,19 @@
+-- Attributes such as material, fit or season are defined per category and inherited
+-- by its subcategories. Enum attributes list their allowed values in options.
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id BIGSERIAL PRIMARY KEY,
+    category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('enum', 'number', 'boolean', 'text')),
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1,
+    UNIQUE (category_id, key)
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+-- jsonb_path_ops supports the @@ jsonpath filters of the product list.
+CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN (attributes jsonb_path_ops);
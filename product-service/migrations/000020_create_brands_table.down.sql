@@ -0,0 +1,15 @@
+DROP TRIGGER IF EXISTS brands_refresh_products ON brands;
+DROP TRIGGER IF EXISTS products_refresh_tsv ON products;
+DROP FUNCTION IF EXISTS refresh_brand_products();
+DROP FUNCTION IF EXISTS refresh_product_tsv();
+
+ALTER TABLE products DROP COLUMN IF EXISTS tsv;
+ALTER TABLE products ADD COLUMN tsv tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+CREATE INDEX idx_products_tsv ON products USING GIN(tsv);
+
+DROP INDEX IF EXISTS idx_products_brand_id;
+ALTER TABLE products DROP COLUMN IF EXISTS brand_id;
+DROP TABLE IF EXISTS brands;
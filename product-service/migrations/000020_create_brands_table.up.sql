@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS brands (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    logo_url TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS brand_id BIGINT REFERENCES brands(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_products_brand_id ON products(brand_id);
+
+-- The search vector now includes the brand's name, weighted like the product's, so it
+-- can no longer be a generated column and is kept up to date by triggers instead.
+ALTER TABLE products DROP COLUMN tsv;
+ALTER TABLE products ADD COLUMN tsv tsvector;
+
+CREATE OR REPLACE FUNCTION refresh_product_tsv() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.tsv :=
+        setweight(to_tsvector('english', NEW.name), 'A') ||
+        setweight(to_tsvector('english', coalesce((SELECT name FROM brands WHERE id = NEW.brand_id), '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.description, '')), 'B');
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_refresh_tsv
+BEFORE INSERT OR UPDATE OF name, description, brand_id ON products
+FOR EACH ROW EXECUTE FUNCTION refresh_product_tsv();
+
+-- Renaming a brand changes its products' search vectors and responses. Setting
+-- brand_id fires the trigger above, and updated_at revalidates cached copies.
+CREATE OR REPLACE FUNCTION refresh_brand_products() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE products SET brand_id = brand_id, updated_at = NOW() WHERE brand_id = NEW.id;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER brands_refresh_products
+AFTER UPDATE OF name, slug ON brands
+FOR EACH ROW
+WHEN (OLD.name IS DISTINCT FROM NEW.name OR OLD.slug IS DISTINCT FROM NEW.slug)
+EXECUTE FUNCTION refresh_brand_products();
+
+UPDATE products SET name = name;
+
+CREATE INDEX idx_products_tsv ON products USING GIN(tsv);
@@ -21,6 +21,7 @@ func (app *application) routes() http.Handler {
 
 	//api endpoint needed by other services to interact with user-service
 	router.MethodFunc(http.MethodGet, "/v1/users/{id}", app.requirePermission("products:read", app.getUserHandler))
+	router.MethodFunc(http.MethodPost, "/v1/users/{id}/notifications", app.requirePermission("notifications:send", app.notifyUserHandler))
 
 	// Register routes with method, URL patterns, and handler functions
 	// app.requireActivatedUser
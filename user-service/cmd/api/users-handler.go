@@ -260,3 +260,64 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// notifyUserHandler emails a message to a user on behalf of another service, so that
+// services don't need to know users' email addresses. The email is sent in the
+// background.
+func (app *application) notifyUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Subject string `json:"subject"`
+		Message string `json:"message"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.Subject != "", "subject", "must be provided")
+	v.Check(len(input.Subject) <= 200, "subject", "must not exceed 200 characters")
+	v.Check(input.Message != "", "message", "must be provided")
+	v.Check(len(input.Message) <= 5000, "message", "must not exceed 5000 characters")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetUserID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.background(func() {
+		data := map[string]interface{}{
+			"name":    user.Name,
+			"subject": input.Subject,
+			"message": input.Message,
+		}
+
+		err := app.mailer.Send(user.Email, "user_notification.html", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"message": "notification will be sent"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,2 @@
+INSERT INTO permissions (code) VALUES ('orders:admin')
+ON CONFLICT (code) DO NOTHING;
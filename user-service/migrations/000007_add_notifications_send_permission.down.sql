@@ -0,0 +1,3 @@
+DELETE FROM users_permissions
+WHERE permission_id IN (SELECT id FROM permissions WHERE code = 'notifications:send');
+DELETE FROM permissions WHERE code = 'notifications:send';
@@ -0,0 +1,3 @@
+-- Held by the service accounts of other services that email users through user-service.
+INSERT INTO permissions (code) VALUES ('notifications:send')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,3 @@
+-- Held by the staff who approve products submitted for review in product-service.
+INSERT INTO permissions (code) VALUES ('products:moderate')
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,4 @@
+-- Held by product-service's service account, which reads which products are bought
+-- together from order-service.
+INSERT INTO permissions (code) VALUES ('orders:co-purchases')
+ON CONFLICT (code) DO NOTHING;
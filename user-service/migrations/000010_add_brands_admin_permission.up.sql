@@ -0,0 +1,3 @@
+-- Lets a user create, edit and delete brands in product-service.
+INSERT INTO permissions (code) VALUES ('brands:admin')
+ON CONFLICT (code) DO NOTHING;